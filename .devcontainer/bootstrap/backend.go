@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBackend       = "local"
+	defaultLocalPath     = "statefile/terraform.tfstate"
+	defaultGitLabAddress = "https://gitlab.com"
+)
+
+var (
+	azureStorageAccountRe = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	azureContainerRe      = regexp.MustCompile(`^[a-z0-9]([a-z0-9]|-[a-z0-9]){2,62}$`)
+	s3BucketRe            = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+)
+
+// Backend generates the backend block of a service's Terraform configuration.
+type Backend interface {
+	// Type is the Terraform backend type, e.g. "azurerm".
+	Type() string
+	// Attributes returns the backend block attributes for the service. A
+	// non-empty env selects the state of that environment, which must not
+	// be shared with any other environment.
+	Attributes(s *Service, env string) []hclAttribute
+}
+
+// backendFactory builds a backend from its validated parameters.
+type backendFactory struct {
+	params   []string
+	required []string
+	build    func(params map[string]string) (Backend, error)
+}
+
+var backends = map[string]backendFactory{
+	"local": {
+		params: []string{"path"},
+		build:  newLocalBackend,
+	},
+	"azurerm": {
+		params:   []string{"resource_group_name", "storage_account_name", "container_name", "key"},
+		required: []string{"resource_group_name", "storage_account_name", "container_name"},
+		build:    newAzureRMBackend,
+	},
+	"gitlab": {
+		params:   []string{"address", "project_id", "state_name"},
+		required: []string{"project_id"},
+		build:    newGitLabBackend,
+	},
+	"s3": {
+		params:   []string{"bucket", "key", "region", "endpoint"},
+		required: []string{"bucket", "region"},
+		build:    newS3Backend,
+	},
+}
+
+// BackendNames returns the supported backend kinds.
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBackend validates params against the parameters the backend kind
+// accepts and builds it.
+func NewBackend(kind string, params map[string]string) (Backend, error) {
+	factory, ok := backends[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q, expected one of %s", kind, strings.Join(BackendNames(), ", "))
+	}
+	for name := range params {
+		if !slices.Contains(factory.params, name) {
+			return nil, fmt.Errorf("backend %s does not accept parameter %q, expected one of %s", kind, name, strings.Join(factory.params, ", "))
+		}
+	}
+	for _, name := range factory.required {
+		if params[name] == "" {
+			return nil, fmt.Errorf("backend %s requires parameter %q", kind, name)
+		}
+	}
+	return factory.build(params)
+}
+
+// localBackend keeps the state on disk next to the service.
+type localBackend struct {
+	path string
+}
+
+func newLocalBackend(params map[string]string) (Backend, error) {
+	b := &localBackend{path: params["path"]}
+	if b.path == "" {
+		b.path = defaultLocalPath
+	}
+	return b, nil
+}
+
+func (b *localBackend) Type() string { return "local" }
+
+func (b *localBackend) Attributes(s *Service, env string) []hclAttribute {
+	return []hclAttribute{{"path", hclString(environmentKey(b.path, env))}}
+}
+
+// azureRMBackend stores the state as a blob in an Azure storage account.
+type azureRMBackend struct {
+	resourceGroup  string
+	storageAccount string
+	container      string
+	key            string
+}
+
+func newAzureRMBackend(params map[string]string) (Backend, error) {
+	b := &azureRMBackend{
+		resourceGroup:  params["resource_group_name"],
+		storageAccount: params["storage_account_name"],
+		container:      params["container_name"],
+		key:            params["key"],
+	}
+	if !azureStorageAccountRe.MatchString(b.storageAccount) {
+		return nil, fmt.Errorf("azurerm storage_account_name %q must be 3-24 lowercase letters and digits", b.storageAccount)
+	}
+	if !azureContainerRe.MatchString(b.container) {
+		return nil, fmt.Errorf("azurerm container_name %q must be 3-63 lowercase letters, digits and single hyphens", b.container)
+	}
+	return b, nil
+}
+
+func (b *azureRMBackend) Type() string { return "azurerm" }
+
+func (b *azureRMBackend) Attributes(s *Service, env string) []hclAttribute {
+	key := b.key
+	if key == "" {
+		key = s.Name + ".terraform.tfstate"
+	}
+	key = environmentKey(key, env)
+	return []hclAttribute{
+		{"resource_group_name", hclString(b.resourceGroup)},
+		{"storage_account_name", hclString(b.storageAccount)},
+		{"container_name", hclString(b.container)},
+		{"key", hclString(key)},
+	}
+}
+
+// gitLabBackend uses GitLab-managed Terraform state through the http backend.
+// Credentials are read by Terraform from TF_HTTP_USERNAME/TF_HTTP_PASSWORD.
+type gitLabBackend struct {
+	address   string
+	projectID string
+	stateName string
+}
+
+func newGitLabBackend(params map[string]string) (Backend, error) {
+	b := &gitLabBackend{
+		address:   strings.TrimSuffix(params["address"], "/"),
+		projectID: params["project_id"],
+		stateName: params["state_name"],
+	}
+	if b.address == "" {
+		b.address = defaultGitLabAddress
+	}
+	u, err := url.Parse(b.address)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("gitlab address %q must be an http(s) URL", b.address)
+	}
+	if id, err := strconv.Atoi(b.projectID); err != nil || id <= 0 {
+		return nil, fmt.Errorf("gitlab project_id %q must be a positive number", b.projectID)
+	}
+	return b, nil
+}
+
+func (b *gitLabBackend) Type() string { return "http" }
+
+func (b *gitLabBackend) Attributes(s *Service, env string) []hclAttribute {
+	stateName := b.stateName
+	if stateName == "" {
+		stateName = s.Name
+	}
+	// GitLab state names are flat, so the environment is a suffix.
+	if env != "" {
+		stateName += "-" + env
+	}
+	address := fmt.Sprintf("%s/api/v4/projects/%s/terraform/state/%s", b.address, b.projectID, url.PathEscape(stateName))
+	return []hclAttribute{
+		{"address", hclString(address)},
+		{"lock_address", hclString(address + "/lock")},
+		{"unlock_address", hclString(address + "/lock")},
+		{"lock_method", hclString("POST")},
+		{"unlock_method", hclString("DELETE")},
+		{"retry_wait_min", "5"},
+	}
+}
+
+// s3Backend stores the state in AWS S3 or any S3-compatible object store.
+type s3Backend struct {
+	bucket   string
+	key      string
+	region   string
+	endpoint string
+}
+
+func newS3Backend(params map[string]string) (Backend, error) {
+	b := &s3Backend{
+		bucket:   params["bucket"],
+		key:      params["key"],
+		region:   params["region"],
+		endpoint: params["endpoint"],
+	}
+	if !s3BucketRe.MatchString(b.bucket) {
+		return nil, fmt.Errorf("s3 bucket %q must be 3-63 lowercase letters, digits, dots and hyphens", b.bucket)
+	}
+	if b.endpoint != "" {
+		u, err := url.Parse(b.endpoint)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("s3 endpoint %q must be an http(s) URL", b.endpoint)
+		}
+	}
+	return b, nil
+}
+
+func (b *s3Backend) Type() string { return "s3" }
+
+func (b *s3Backend) Attributes(s *Service, env string) []hclAttribute {
+	key := b.key
+	if key == "" {
+		key = s.Name + "/terraform.tfstate"
+	}
+	key = environmentKey(key, env)
+	attrs := []hclAttribute{
+		{"bucket", hclString(b.bucket)},
+		{"key", hclString(key)},
+		{"region", hclString(b.region)},
+	}
+	if b.endpoint != "" {
+		// S3-compatible stores need path-style access and none of the AWS
+		// account checks.
+		attrs = append(attrs,
+			hclAttribute{"endpoints", fmt.Sprintf("{ s3 = %s }", hclString(b.endpoint))},
+			hclAttribute{"use_path_style", "true"},
+			hclAttribute{"skip_credentials_validation", "true"},
+			hclAttribute{"skip_region_validation", "true"},
+			hclAttribute{"skip_requesting_account_id", "true"},
+		)
+	}
+	return attrs
+}
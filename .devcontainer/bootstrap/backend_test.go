@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func renderBackend(t *testing.T, backend Backend) string {
+	t.Helper()
+	service := NewService("test-service", "test-path", "", "")
+	service.Backend = backend
+
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	content, err := templates.Render("backend.tf.tmpl", service.templateData(nil))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	return content
+}
+
+func TestDefaultBackend(t *testing.T) {
+	content := renderBackend(t, nil)
+	expected := `terraform {
+  backend "local" {
+    path = "statefile/terraform.tfstate"
+  }
+}
+`
+	if content != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	tests := []struct {
+		kind     string
+		params   map[string]string
+		expected []string
+	}{
+		{
+			kind: "azurerm",
+			params: map[string]string{
+				"resource_group_name":  "tfstate",
+				"storage_account_name": "tfstate123",
+				"container_name":       "tfstate",
+			},
+			expected: []string{
+				`backend "azurerm" {`,
+				`    storage_account_name = "tfstate123"`,
+				`    key                  = "test-service.terraform.tfstate"`,
+			},
+		},
+		{
+			kind:   "gitlab",
+			params: map[string]string{"project_id": "42"},
+			expected: []string{
+				`backend "http" {`,
+				`    address        = "https://gitlab.com/api/v4/projects/42/terraform/state/test-service"`,
+				`    lock_address   = "https://gitlab.com/api/v4/projects/42/terraform/state/test-service/lock"`,
+				`    unlock_method  = "DELETE"`,
+			},
+		},
+		{
+			kind:   "s3",
+			params: map[string]string{"bucket": "tfstate", "region": "eu-west-1", "endpoint": "http://minio:9000"},
+			expected: []string{
+				`backend "s3" {`,
+				`    key                         = "test-service/terraform.tfstate"`,
+				`    endpoints                   = { s3 = "http://minio:9000" }`,
+				`    use_path_style              = true`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			backend, err := NewBackend(tt.kind, tt.params)
+			if err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			content := renderBackend(t, backend)
+			for _, line := range tt.expected {
+				if !strings.Contains(content, line) {
+					t.Errorf("expected %q in:\n%s", line, content)
+				}
+			}
+		})
+	}
+}
+
+func TestNewBackendValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		params map[string]string
+	}{
+		{"unknown kind", "consul", nil},
+		{"unknown parameter", "local", map[string]string{"bucket": "x"}},
+		{"missing parameter", "azurerm", map[string]string{"resource_group_name": "rg"}},
+		{"invalid storage account", "azurerm", map[string]string{
+			"resource_group_name": "rg", "storage_account_name": "Invalid_Name", "container_name": "tfstate",
+		}},
+		{"invalid project id", "gitlab", map[string]string{"project_id": "group/project"}},
+		{"invalid address", "gitlab", map[string]string{"project_id": "1", "address": "gitlab.com"}},
+		{"invalid bucket", "s3", map[string]string{"bucket": "B", "region": "us-east-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBackend(tt.kind, tt.params); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
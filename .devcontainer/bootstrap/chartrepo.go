@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+const chartRequestTimeout = 30 * time.Second
+
+// RepoIndex is the subset of a Helm repository index.yaml bootstrap uses.
+type RepoIndex struct {
+	APIVersion string                     `yaml:"apiVersion"`
+	Entries    map[string][]*ChartVersion `yaml:"entries"`
+}
+
+// ChartVersion is a single chart version listed in a repository index.
+type ChartVersion struct {
+	Name       string   `yaml:"name"`
+	Version    string   `yaml:"version"`
+	AppVersion string   `yaml:"appVersion"`
+	Deprecated bool     `yaml:"deprecated"`
+	URLs       []string `yaml:"urls"`
+	Digest     string   `yaml:"digest"`
+}
+
+// ChartResolver looks charts up in their repository.
+type ChartResolver struct {
+	Client *http.Client
+
+	// Username and Password authenticate against OCI registries.
+	Username string
+	Password string
+	// PlainHTTP talks to OCI registries over HTTP instead of HTTPS, like
+	// helm --plain-http, e.g. for a local registry on localhost:5000.
+	PlainHTTP bool
+
+	mu      sync.Mutex
+	indexes map[string]*RepoIndex
+}
+
+// NewChartResolver creates a ChartResolver with a bounded request timeout.
+func NewChartResolver() *ChartResolver {
+	return &ChartResolver{Client: &http.Client{Timeout: chartRequestTimeout}}
+}
+
+// Resolve checks that chart exists in its repository and returns it with
+// Version pinned. An empty chart.Version selects the latest stable version,
+// anything else is treated as a semver constraint, e.g. "13.0.0" or "^13".
+// Classic repositories are looked up in their index.yaml, OCI registries
+// through their tag list.
+func (r *ChartResolver) Resolve(chart Chart) (Chart, error) {
+	chart = chart.splitOCI()
+	if chart.IsOCI() {
+		tags, err := r.ListTags(chart)
+		if err != nil {
+			return chart, err
+		}
+		version, err := selectVersion(chart.Name, tags, chart.Version)
+		if err != nil {
+			return chart, fmt.Errorf("repository %s: %w", chart.Repository, err)
+		}
+		chart.Version = version
+		return chart, nil
+	}
+
+	index, err := r.FetchIndex(chart.Repository)
+	if err != nil {
+		return chart, err
+	}
+	version, err := index.Find(chart.Name, chart.Version)
+	if err != nil {
+		return chart, fmt.Errorf("repository %s: %w", chart.Repository, err)
+	}
+	chart.Version = version.Version
+	return chart, nil
+}
+
+// FetchIndex downloads and parses the index.yaml of the repository. Indexes
+// are cached for the lifetime of the resolver.
+func (r *ChartResolver) FetchIndex(repository string) (*RepoIndex, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if index, ok := r.indexes[repository]; ok {
+		return index, nil
+	}
+
+	indexURL := strings.TrimSuffix(repository, "/") + "/index.yaml"
+	resp, err := r.Client.Get(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", indexURL, resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index RepoIndex
+	if err := yaml.NewDecoder(bytes.NewReader(content)).Decode(&index); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", indexURL, err)
+	}
+	if r.indexes == nil {
+		r.indexes = make(map[string]*RepoIndex)
+	}
+	r.indexes[repository] = &index
+	return &index, nil
+}
+
+// Find returns the highest version of the named chart that satisfies
+// constraint. Deprecated versions are skipped.
+func (i *RepoIndex) Find(name, constraint string) (*ChartVersion, error) {
+	entries, ok := i.Entries[name]
+	if !ok || len(entries) == 0 {
+		return nil, fmt.Errorf("chart %q not found", name)
+	}
+
+	byVersion := make(map[string]*ChartVersion, len(entries))
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Deprecated {
+			continue
+		}
+		byVersion[entry.Version] = entry
+		versions = append(versions, entry.Version)
+	}
+	version, err := selectVersion(name, versions, constraint)
+	if err != nil {
+		return nil, err
+	}
+	return byVersion[version], nil
+}
+
+// selectVersion returns the highest of versions that satisfies constraint.
+// With no constraint the highest version without a pre-release suffix wins.
+// Entries that are not semantic versions are ignored.
+func selectVersion(name string, versions []string, constraint string) (string, error) {
+	var c *semver.Constraints
+	if constraint != "" {
+		var err error
+		if c, err = semver.NewConstraint(constraint); err != nil {
+			return "", fmt.Errorf("chart %q: invalid version constraint %q: %w", name, constraint, err)
+		}
+	}
+
+	var best *semver.Version
+	var bestRaw string
+	for _, raw := range versions {
+		version, err := semver.NewVersion(raw)
+		if err != nil {
+			continue
+		}
+		if c == nil && version.Prerelease() != "" {
+			continue
+		}
+		if c != nil && !c.Check(version) {
+			continue
+		}
+		if best == nil || version.GreaterThan(best) {
+			best, bestRaw = version, raw
+		}
+	}
+	if best == nil {
+		if constraint == "" {
+			return "", fmt.Errorf("chart %q has no stable version", name)
+		}
+		return "", fmt.Errorf("chart %q has no version matching %q", name, constraint)
+	}
+	return bestRaw, nil
+}
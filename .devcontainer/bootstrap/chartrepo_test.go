@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRepoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveChart(t *testing.T) {
+	server := newRepoServer(t)
+	resolver := &ChartResolver{Client: server.Client()}
+
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{"", "14.2.1"},
+		{"13.0.0", "13.0.0"},
+		{"^13", "13.2.4"},
+		{"~14.1", ""},
+		{">=15.0.0-0", "15.0.0-rc.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			chart, err := resolver.Resolve(Chart{Name: "nginx", Repository: server.URL + "/", Version: tt.constraint})
+			if tt.expected == "" {
+				if err == nil {
+					t.Errorf("expected an error, got version '%s'", chart.Version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			if chart.Version != tt.expected {
+				t.Errorf("expected version '%s', got '%s'", tt.expected, chart.Version)
+			}
+		})
+	}
+}
+
+func TestResolveChartErrors(t *testing.T) {
+	server := newRepoServer(t)
+	resolver := &ChartResolver{Client: server.Client()}
+
+	if _, err := resolver.Resolve(Chart{Name: "missing", Repository: server.URL}); err == nil {
+		t.Errorf("expected an error for a missing chart")
+	}
+	if _, err := resolver.Resolve(Chart{Name: "nginx", Repository: server.URL, Version: "not a constraint"}); err == nil {
+		t.Errorf("expected an error for an invalid constraint")
+	}
+	if _, err := resolver.Resolve(Chart{Name: "nginx", Repository: server.URL + "/missing"}); err == nil {
+		t.Errorf("expected an error for a missing index")
+	}
+}
+
+func TestResolvedVersionInTfvars(t *testing.T) {
+	server := newRepoServer(t)
+	resolver := &ChartResolver{Client: server.Client()}
+
+	service := NewService("redis", "test-path", "", "")
+	service.Chart.Repository = server.URL
+	chart, err := resolver.Resolve(service.Chart)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	service.Chart = chart
+
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	content, err := templates.Render("terraform.tfvars.tmpl", service.templateData(nil))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := `chart_version  = "18.0.0"`
+	if !strings.Contains(content, expected) {
+		t.Errorf("expected %q in:\n%s", expected, content)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// envDir is the directory, relative to the service, holding the variable
+// and backend files of every environment.
+const envDir = "env"
+
+// environmentNameRe restricts environment names to what is safe in file
+// names and state keys.
+var environmentNameRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`)
+
+// Environment is a cluster a service is promoted to. Every environment has
+// its own variable file and state; empty fields fall back to the service's.
+type Environment struct {
+	Name       string `yaml:"name"`
+	KubeConfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context"`
+	Namespace  string `yaml:"namespace"`
+}
+
+// ParseEnvironments parses a comma-separated list of environments, each
+// either a name or name=context, e.g. "dev=kind-dev,prod=aks-prod".
+func ParseEnvironments(list string) ([]Environment, error) {
+	var environments []Environment
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, context, _ := strings.Cut(item, "=")
+		environments = append(environments, Environment{Name: name, Context: context})
+	}
+	if err := validateEnvironments(environments); err != nil {
+		return nil, err
+	}
+	return environments, nil
+}
+
+// validateEnvironments rejects invalid and duplicate environment names.
+func validateEnvironments(environments []Environment) error {
+	seen := make(map[string]bool, len(environments))
+	for i, env := range environments {
+		if err := validateEnvironmentName(env.Name); err != nil {
+			return fmt.Errorf("environments[%d]: %w", i, err)
+		}
+		if seen[env.Name] {
+			return fmt.Errorf("environments[%d]: duplicate environment %q", i, env.Name)
+		}
+		seen[env.Name] = true
+	}
+	return nil
+}
+
+// validateEnvironmentName rejects names that are unsafe in file names.
+func validateEnvironmentName(name string) error {
+	if !environmentNameRe.MatchString(name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits, hyphens and underscores", name)
+	}
+	return nil
+}
+
+// environments returns the service's environments with empty fields filled
+// in from the service.
+func (s *Service) environments() []Environment {
+	environments := make([]Environment, len(s.Environments))
+	for i, env := range s.Environments {
+		environments[i] = Environment{
+			Name:       env.Name,
+			KubeConfig: firstNonEmpty(env.KubeConfig, s.KubeConfig),
+			Context:    firstNonEmpty(env.Context, s.Context),
+			Namespace:  firstNonEmpty(env.Namespace, s.Namespace),
+		}
+	}
+	return environments
+}
+
+// EnvironmentNames returns the names of the service's environments.
+func (s *Service) EnvironmentNames() []string {
+	names := make([]string, len(s.Environments))
+	for i, env := range s.Environments {
+		names[i] = env.Name
+	}
+	return names
+}
+
+// envVarFile returns the variable file of env relative to the service
+// directory.
+func envVarFile(env string) string {
+	return envDir + "/" + env + ".tfvars"
+}
+
+// envBackendFile returns the backend configuration file of env relative to
+// the service directory.
+func envBackendFile(env string) string {
+	return envDir + "/" + env + ".backend.hcl"
+}
+
+// environmentKey places the state key of env in a directory of its own next
+// to key, e.g. web/terraform.tfstate becomes web/prod/terraform.tfstate.
+func environmentKey(key, env string) string {
+	if env == "" {
+		return key
+	}
+	return path.Join(path.Dir(key), env, path.Base(key))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvironments(t *testing.T) {
+	envs, err := ParseEnvironments("dev, staging,prod=aks-prod")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := []Environment{{Name: "dev"}, {Name: "staging"}, {Name: "prod", Context: "aks-prod"}}
+	if len(envs) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, envs)
+	}
+	for i := range expected {
+		if envs[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], envs[i])
+		}
+	}
+
+	for _, list := range []string{"dev,dev", "Prod", "dev/eu", "=ctx"} {
+		if _, err := ParseEnvironments(list); err == nil {
+			t.Errorf("expected an error for %q", list)
+		}
+	}
+}
+
+func TestEnvironmentKey(t *testing.T) {
+	tests := []struct{ key, env, expected string }{
+		{"statefile/terraform.tfstate", "", "statefile/terraform.tfstate"},
+		{"statefile/terraform.tfstate", "dev", "statefile/dev/terraform.tfstate"},
+		{"web.terraform.tfstate", "prod", "prod/web.terraform.tfstate"},
+	}
+	for _, tt := range tests {
+		if got := environmentKey(tt.key, tt.env); got != tt.expected {
+			t.Errorf("environmentKey(%q, %q): expected %q, got %q", tt.key, tt.env, tt.expected, got)
+		}
+	}
+}
+
+func TestEnvironmentFiles(t *testing.T) {
+	service := NewService("web", "test-path", "~/.kube/config", "kind-dev")
+	service.Environments = []Environment{{Name: "dev"}, {Name: "prod", Context: "aks-prod", Namespace: "web-prod"}}
+	service.Backend, _ = NewBackend("s3", map[string]string{"bucket": "tfstate", "region": "eu-west-1"})
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	contents := map[string]string{}
+	for _, file := range files {
+		rel, _ := filepath.Rel(service.Dir(), file.path)
+		contents[filepath.ToSlash(rel)] = file.content
+	}
+
+	expected := map[string][]string{
+		"backend.tf":           {`backend "s3" {}`},
+		"variables.tf":         {`variable "environment" {`, `contains(["dev", "prod"], var.environment)`},
+		"env/dev.tfvars":       {`environment    = "dev"`, `config_context = "kind-dev"`, `namespace      = "web"`},
+		"env/prod.tfvars":      {`environment    = "prod"`, `config_context = "aks-prod"`, `namespace      = "web-prod"`},
+		"env/dev.backend.hcl":  {`key    = "web/dev/terraform.tfstate"`},
+		"env/prod.backend.hcl": {`bucket = "tfstate"`, `key    = "web/prod/terraform.tfstate"`},
+	}
+	for file, lines := range expected {
+		content, ok := contents[file]
+		if !ok {
+			t.Errorf("expected %s to be generated, got %v", file, files)
+			continue
+		}
+		for _, line := range lines {
+			if !strings.Contains(content, line) {
+				t.Errorf("expected %q in %s:\n%s", line, file, content)
+			}
+		}
+	}
+}
+
+func TestNoEnvironmentFilesByDefault(t *testing.T) {
+	service := NewService("web", "test-path", "", "")
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, file := range files {
+		if strings.Contains(filepath.ToSlash(file.path), "/"+envDir+"/") {
+			t.Errorf("expected no environment files, got %s", file.path)
+		}
+		if filepath.Base(file.path) == "variables.tf" && strings.Contains(file.content, `"environment"`) {
+			t.Errorf("expected no environment variable, got:\n%s", file.content)
+		}
+	}
+}
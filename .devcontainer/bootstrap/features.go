@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Features are the opt-in guardrails generated next to a service's Helm
+// release. Every feature manages the namespace through the kubernetes
+// provider instead of letting Helm create it.
+type Features struct {
+	// Namespace manages the namespace with the standard labels.
+	Namespace bool
+	// ResourceQuota caps the resources the namespace may request.
+	ResourceQuota bool
+	// LimitRange gives containers default requests and limits.
+	LimitRange bool
+	// NetworkPolicy denies all traffic that is not explicitly allowed,
+	// except DNS lookups.
+	NetworkPolicy bool
+}
+
+// featureFlags maps feature names to the field they enable.
+var featureFlags = map[string]func(*Features){
+	"namespace":      func(f *Features) { f.Namespace = true },
+	"resource-quota": func(f *Features) { f.ResourceQuota = true },
+	"limit-range":    func(f *Features) { f.LimitRange = true },
+	"network-policy": func(f *Features) { f.NetworkPolicy = true },
+}
+
+// FeatureNames returns the supported feature names.
+func FeatureNames() []string {
+	names := make([]string, 0, len(featureFlags))
+	for name := range featureFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFeatures enables the named features. "all" enables every feature.
+func ParseFeatures(names []string) (Features, error) {
+	var f Features
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			for _, enable := range featureFlags {
+				enable(&f)
+			}
+			continue
+		}
+		enable, ok := featureFlags[name]
+		if !ok {
+			return f, fmt.Errorf("unknown feature %q, expected one of %s or all", name, strings.Join(FeatureNames(), ", "))
+		}
+		enable(&f)
+	}
+	return f, nil
+}
+
+// Kubernetes reports whether any feature needs the kubernetes provider, in
+// which case the namespace is managed by Terraform.
+func (f Features) Kubernetes() bool {
+	return f.Namespace || f.ResourceQuota || f.LimitRange || f.NetworkPolicy
+}
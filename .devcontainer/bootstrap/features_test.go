@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFeatures(t *testing.T) {
+	features, err := ParseFeatures([]string{"namespace", " network-policy", ""})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if features != (Features{Namespace: true, NetworkPolicy: true}) {
+		t.Errorf("unexpected features %+v", features)
+	}
+
+	all, err := ParseFeatures([]string{"all"})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if all != (Features{Namespace: true, ResourceQuota: true, LimitRange: true, NetworkPolicy: true}) {
+		t.Errorf("expected every feature, got %+v", all)
+	}
+
+	if _, err := ParseFeatures([]string{"pod-security"}); err == nil {
+		t.Errorf("expected an error for an unknown feature")
+	}
+}
+
+func renderFeatureFiles(t *testing.T, features Features) map[string]string {
+	t.Helper()
+	service := NewService("web", "test-path", "", "")
+	service.Features = features
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	contents := map[string]string{}
+	for _, file := range files {
+		contents[filepath.Base(file.path)] = file.content
+	}
+	return contents
+}
+
+func TestFeaturesDisabled(t *testing.T) {
+	contents := renderFeatureFiles(t, Features{})
+
+	if _, ok := contents["namespace.tf"]; ok {
+		t.Errorf("expected no namespace.tf without features")
+	}
+	if !strings.Contains(contents["main.tf"], "create_namespace    = true") {
+		t.Errorf("expected Helm to create the namespace, got:\n%s", contents["main.tf"])
+	}
+	if strings.Contains(contents["providers.tf"], `provider "kubernetes"`) {
+		t.Errorf("expected no kubernetes provider, got:\n%s", contents["providers.tf"])
+	}
+}
+
+func TestFeaturesEnabled(t *testing.T) {
+	contents := renderFeatureFiles(t, Features{Namespace: true, ResourceQuota: true, LimitRange: true, NetworkPolicy: true})
+
+	expected := map[string][]string{
+		"namespace.tf": {
+			`resource "kubernetes_namespace" "this" {`,
+			`"app.kubernetes.io/managed-by" = "terraform"`,
+			`resource "kubernetes_resource_quota" "this" {`,
+			`resource "kubernetes_limit_range" "this" {`,
+			`resource "kubernetes_network_policy" "default_deny" {`,
+		},
+		"main.tf": {
+			"namespace           = kubernetes_namespace.this.metadata[0].name",
+			"create_namespace    = false",
+			"kubernetes_network_policy.default_deny,",
+		},
+		"providers.tf": {`provider "kubernetes" {`, `config_context = var.config_context`},
+		"terraform.tf": {`source  = "hashicorp/kubernetes"`},
+		"variables.tf": {`variable "resource_quota" {`, `variable "container_default_limits" {`},
+	}
+	for file, lines := range expected {
+		for _, line := range lines {
+			if !strings.Contains(contents[file], line) {
+				t.Errorf("expected %q in %s:\n%s", line, file, contents[file])
+			}
+		}
+	}
+}
+
+func TestNamespaceFeatureOnly(t *testing.T) {
+	contents := renderFeatureFiles(t, Features{Namespace: true})
+
+	if strings.Contains(contents["namespace.tf"], "kubernetes_resource_quota") {
+		t.Errorf("expected only the namespace, got:\n%s", contents["namespace.tf"])
+	}
+	if strings.Contains(contents["main.tf"], "depends_on") {
+		t.Errorf("expected no guardrail dependencies, got:\n%s", contents["main.tf"])
+	}
+}
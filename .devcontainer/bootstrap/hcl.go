@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// hclExts are the extensions of generated files in HCL native syntax.
+var hclExts = map[string]bool{".tf": true, ".tfvars": true, ".hcl": true}
+
+// hclAttribute is a single attribute of a generated block. Value is an HCL
+// expression and is written verbatim.
+type hclAttribute struct {
+	Name  string
+	Value string
+}
+
+// isHCL reports whether the file at path is written in HCL native syntax.
+func isHCL(path string) bool {
+	return hclExts[filepath.Ext(path)]
+}
+
+// formatHCL parses content and returns it formatted the way terraform fmt
+// does. Malformed content is reported with file:line diagnostics.
+func formatHCL(filename string, content []byte) ([]byte, error) {
+	if _, diags := hclparse.NewParser().ParseHCL(content, filename); diags.HasErrors() {
+		return nil, diags
+	}
+	if _, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos); diags.HasErrors() {
+		return nil, diags
+	}
+	return hclwrite.Format(content), nil
+}
+
+// hclString quotes s as an HCL string literal, escaping quotes, control
+// characters and template sequences.
+func hclString(s string) string {
+	return string(hclwrite.TokensForValue(cty.StringVal(s)).Bytes())
+}
+
+// hclIdentifier turns name into an identifier that can be used to refer to
+// a block, e.g. "web.v2" becomes "web_v2" and "2048" becomes "_2048".
+func hclIdentifier(name string) string {
+	if hclsyntax.ValidIdentifier(name) {
+		return name
+	}
+	ident := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+	if !hclsyntax.ValidIdentifier(ident) {
+		ident = "_" + ident
+	}
+	return ident
+}
+
+// hclList renders values as an HCL list of strings.
+func hclList(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	elems := make([]cty.Value, len(values))
+	for i, v := range values {
+		elems[i] = cty.StringVal(v)
+	}
+	return string(hclwrite.TokensForValue(cty.ListVal(elems)).Bytes())
+}
+
+// hclAttributes renders attrs one per line, indented by indent spaces and
+// with their equals signs aligned the way terraform fmt does.
+func hclAttributes(indent int, attrs []hclAttribute) string {
+	width := 0
+	for _, attr := range attrs {
+		width = max(width, len(attr.Name))
+	}
+	lines := make([]string, len(attrs))
+	for i, attr := range attrs {
+		lines[i] = fmt.Sprintf("%s%-*s = %s", strings.Repeat(" ", indent), width, attr.Name, attr.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readTfvars returns the string variables set by the variable files at
+// paths. Later files override earlier ones, as with terraform -var-file.
+func readTfvars(paths ...string) (map[string]string, error) {
+	vars := map[string]string{}
+	parser := hclparse.NewParser()
+	for _, path := range paths {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attrs, diags := file.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for name, attr := range attrs {
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || value.Type() != cty.String || value.IsNull() {
+				continue
+			}
+			vars[name] = value.AsString()
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+func TestHCLString(t *testing.T) {
+	tests := map[string]string{
+		`plain`:             `"plain"`,
+		`we"b`:              `"we\"b"`,
+		`C:\kube\config`:    `"C:\\kube\\config"`,
+		`${var.injected}`:   `"$${var.injected}"`,
+		"line\nbreak":       `"line\nbreak"`,
+		`%{ if true }x%{ }`: `"%%{ if true }x%%{ }"`,
+	}
+	for input, expected := range tests {
+		if got := hclString(input); got != expected {
+			t.Errorf("hclString(%q): expected %s, got %s", input, expected, got)
+		}
+	}
+}
+
+func TestGeneratedFilesAreValidHCL(t *testing.T) {
+	service := NewService(`we"b`, "test-path", `/home/me/.kube/my "cluster" ${HOME}`, "ctx\\1")
+	service.ValuesFiles = []string{"values/a b.yaml"}
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, file := range files {
+		parsed, diags := hclparse.NewParser().ParseHCL([]byte(file.content), file.path)
+		if diags.HasErrors() {
+			t.Errorf("expected %s to be valid HCL, got '%v'", file.path, diags)
+			continue
+		}
+		if filepath.Base(file.path) != "terraform.tfvars" {
+			continue
+		}
+		attrs, _ := parsed.Body.JustAttributes()
+		value, _ := attrs["kubeconfig"].Expr.Value(nil)
+		if value.AsString() != service.KubeConfig {
+			t.Errorf("expected kubeconfig %q to round-trip, got %q", service.KubeConfig, value.AsString())
+		}
+	}
+}
+
+func TestGeneratedFilesAreFormatted(t *testing.T) {
+	dir := t.TempDir()
+	template := "variable \"a\" {\n type = string\n      default = {{ hcl .Name }}\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "variables.tf.tmpl"), []byte(template), 0644); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	service := NewService("web", "test-path", "", "")
+	service.Templates = templates
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := "variable \"a\" {\n  type    = string\n  default = \"web\"\n}\n"
+	if files[0].content != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, files[0].content)
+	}
+}
+
+func TestMalformedTemplateReportsLocation(t *testing.T) {
+	dir := t.TempDir()
+	template := "resource \"helm_release\" \"{{ .Name }}\" {\n  name = \"unterminated\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tf.tmpl"), []byte(template), 0644); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	service := NewService("web", "test-path", "", "")
+	service.Templates = templates
+	_, err = service.getTerraformFiles()
+	if err == nil {
+		t.Fatalf("expected an error for malformed HCL")
+	}
+	location := filepath.Join("test-path", "web", "main.tf") + ":2,"
+	if !strings.Contains(err.Error(), location) {
+		t.Errorf("expected the error to point at %s, got '%v'", location, err)
+	}
+}
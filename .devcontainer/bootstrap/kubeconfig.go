@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ContextNotFoundError is returned when a kubeconfig does not define the
+// requested context.
+type ContextNotFoundError struct {
+	Context    string
+	KubeConfig string
+	// Available are the contexts the kubeconfig does define, sorted.
+	Available []string
+}
+
+func (e *ContextNotFoundError) Error() string {
+	if e.Context == "" {
+		return fmt.Sprintf("%s has no current context, pass one of: %s", e.KubeConfig, e.available())
+	}
+	return fmt.Sprintf("context %q not found in %s, available contexts: %s", e.Context, e.KubeConfig, e.available())
+}
+
+func (e *ContextNotFoundError) available() string {
+	if len(e.Available) == 0 {
+		return "none"
+	}
+	return strings.Join(e.Available, ", ")
+}
+
+// KubeConfigs loads kubeconfig files and checks contexts against them.
+// Each distinct kubeconfig is only read once.
+type KubeConfigs struct {
+	loaded map[string]*clientcmdapi.Config
+}
+
+// NewKubeConfigs creates an empty KubeConfigs.
+func NewKubeConfigs() *KubeConfigs {
+	return &KubeConfigs{loaded: make(map[string]*clientcmdapi.Config)}
+}
+
+// Load reads kubeConfig, a list of paths separated like $KUBECONFIG. The
+// files are merged the way kubectl merges them: the first file to define a
+// context, cluster or user wins.
+func (k *KubeConfigs) Load(kubeConfig string) (*clientcmdapi.Config, error) {
+	if config, ok := k.loaded[kubeConfig]; ok {
+		return config, nil
+	}
+	paths := kubeConfigPaths(kubeConfig)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no kubeconfig given")
+	}
+	// Like kubectl, missing files in a list are ignored, but not all of them.
+	if !slices.ContainsFunc(paths, fileExists) {
+		return nil, fmt.Errorf("kubeconfig %s does not exist", kubeConfig)
+	}
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: paths}
+	config, err := rules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig %s: %w", kubeConfig, err)
+	}
+	k.loaded[kubeConfig] = config
+	return config, nil
+}
+
+// ResolveContext checks that context is defined in kubeConfig and returns
+// it. An empty context resolves to the kubeconfig's current context.
+func (k *KubeConfigs) ResolveContext(kubeConfig, context string) (string, error) {
+	config, err := k.Load(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+	name := firstNonEmpty(context, config.CurrentContext)
+	if _, ok := config.Contexts[name]; ok && name != "" {
+		return name, nil
+	}
+	available := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		available = append(available, name)
+	}
+	slices.Sort(available)
+	return "", &ContextNotFoundError{Context: name, KubeConfig: kubeConfig, Available: available}
+}
+
+// ResolveService resolves the contexts of service and of its environments.
+// Environments without a context of their own inherit the service's.
+func (k *KubeConfigs) ResolveService(service *Service) error {
+	context, err := k.ResolveContext(service.KubeConfig, service.Context)
+	if err != nil {
+		return err
+	}
+	service.Context = context
+
+	// Services may share their list of environments.
+	service.Environments = slices.Clone(service.Environments)
+	for i, env := range service.Environments {
+		if env.KubeConfig == "" && env.Context == "" {
+			continue
+		}
+		kubeConfig := firstNonEmpty(env.KubeConfig, service.KubeConfig)
+		context, err := k.ResolveContext(kubeConfig, firstNonEmpty(env.Context, service.Context))
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", env.Name, err)
+		}
+		service.Environments[i].Context = context
+	}
+	return nil
+}
+
+// DefaultContexts fills the empty contexts of service and of its
+// environments with the current context of their kubeconfig, without
+// checking them. Contexts with nothing to resolve to stay empty.
+func (k *KubeConfigs) DefaultContexts(service *Service) {
+	if service.Context == "" {
+		service.Context = k.currentContext(service.KubeConfig)
+	}
+	// Environments with a kubeconfig of their own inherit the service's
+	// context unless it is empty.
+	service.Environments = slices.Clone(service.Environments)
+	for i, env := range service.Environments {
+		if env.KubeConfig != "" && env.Context == "" && service.Context == "" {
+			service.Environments[i].Context = k.currentContext(env.KubeConfig)
+		}
+	}
+}
+
+// currentContext returns the current context of kubeConfig, or "" when it
+// cannot be read.
+func (k *KubeConfigs) currentContext(kubeConfig string) string {
+	config, err := k.Load(kubeConfig)
+	if err != nil {
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// NewKubeClient creates a clientset for context of kubeConfig. An empty
+// context selects the kubeconfig's current context.
+func NewKubeClient(kubeConfig, context string) (kubernetes.Interface, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: kubeConfigPaths(kubeConfig)}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig %s: %w", kubeConfig, err)
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// kubeConfigPaths splits a $KUBECONFIG style list and expands "~" and
+// environment variables in every entry. Empty entries are dropped.
+func kubeConfigPaths(kubeConfig string) []string {
+	var paths []string
+	for _, path := range filepath.SplitList(kubeConfig) {
+		if path = expandPath(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// expandPath expands a leading "~" to the home directory and any
+// environment variables in path.
+func expandPath(path string) string {
+	path = os.ExpandEnv(strings.TrimSpace(path))
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// defaultKubeConfig returns $KUBECONFIG, or the kubeconfig kubectl reads
+// when it is unset.
+func defaultKubeConfig() string {
+	return envOr(clientcmd.RecommendedConfigPathEnvVar, defaultKubeConfigPath)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveContext(t *testing.T) {
+	dev := filepath.Join("testdata", "kube", "config")
+	prod := filepath.Join("testdata", "kube", "prod")
+	merged := dev + string(filepath.ListSeparator) + prod
+
+	tests := []struct {
+		name       string
+		kubeConfig string
+		context    string
+		expected   string
+	}{
+		{"current context", dev, "", "kind-dev"},
+		{"explicit context", dev, "kind-dev", "kind-dev"},
+		{"merged files", merged, "aks-prod", "aks-prod"},
+		{"first file wins the current context", merged, "", "kind-dev"},
+		{"missing files in a list are ignored", "testdata/kube/missing" + string(filepath.ListSeparator) + prod, "", "aks-prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context, err := NewKubeConfigs().ResolveContext(tt.kubeConfig, tt.context)
+			if err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			if context != tt.expected {
+				t.Errorf("expected context '%s', got '%s'", tt.expected, context)
+			}
+		})
+	}
+}
+
+func TestResolveContextNotFound(t *testing.T) {
+	merged := filepath.Join("testdata", "kube", "config") + string(filepath.ListSeparator) + filepath.Join("testdata", "kube", "prod")
+
+	_, err := NewKubeConfigs().ResolveContext(merged, "docker-desktop")
+	var notFound *ContextNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected a ContextNotFoundError, got '%v'", err)
+	}
+	if strings.Join(notFound.Available, ",") != "aks-prod,kind-dev" {
+		t.Errorf("expected the contexts of both files, got %v", notFound.Available)
+	}
+	if !strings.Contains(err.Error(), "available contexts: aks-prod, kind-dev") {
+		t.Errorf("expected the available contexts in '%v'", err)
+	}
+
+	if _, err := NewKubeConfigs().ResolveContext("testdata/kube/missing", ""); err == nil {
+		t.Errorf("expected an error for a missing kubeconfig")
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	t.Setenv("KUBE_DIR", "/etc/kube")
+
+	tests := []struct{ path, expected string }{
+		{"~/.kube/config", filepath.Join(home, ".kube", "config")},
+		{"$KUBE_DIR/config", "/etc/kube/config"},
+		{"/abs/~/config", "/abs/~/config"},
+	}
+	for _, tt := range tests {
+		if got := expandPath(tt.path); got != tt.expected {
+			t.Errorf("expandPath(%q): expected %q, got %q", tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveService(t *testing.T) {
+	service := NewService("web", "test-path", filepath.Join("testdata", "kube", "config"), "")
+	service.Environments = []Environment{
+		{Name: "dev"},
+		{Name: "prod", KubeConfig: filepath.Join("testdata", "kube", "prod"), Context: "aks-prod"},
+	}
+
+	if err := NewKubeConfigs().ResolveService(service); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if service.Context != "kind-dev" {
+		t.Errorf("expected the current context, got '%s'", service.Context)
+	}
+
+	service.Environments[1].Context = "kind-staging"
+	err := NewKubeConfigs().ResolveService(service)
+	if err == nil || !strings.Contains(err.Error(), "environment prod") {
+		t.Errorf("expected an error naming the environment, got '%v'", err)
+	}
+}
+
+func TestDefaultContexts(t *testing.T) {
+	service := NewService("web", "test-path", filepath.Join("testdata", "kube", "config"), "")
+	service.Environments = []Environment{{Name: "dev"}}
+	NewKubeConfigs().DefaultContexts(service)
+	if service.Context != "kind-dev" {
+		t.Errorf("expected the current context, got '%s'", service.Context)
+	}
+
+	// Nothing to resolve leaves the context empty instead of failing.
+	service = NewService("web", "test-path", filepath.Join("testdata", "kube", "missing"), "")
+	service.Environments = []Environment{{Name: "prod", KubeConfig: filepath.Join("testdata", "kube", "prod")}}
+	NewKubeConfigs().DefaultContexts(service)
+	if service.Context != "" || service.Environments[0].Context != "aks-prod" {
+		t.Errorf("expected no service context and the prod current context, got '%s' and '%s'", service.Context, service.Environments[0].Context)
+	}
+}
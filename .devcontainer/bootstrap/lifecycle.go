@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const defaultPlanFile = "tfplan"
+
+// ErrNotApproved is returned when the operator declines to apply a plan.
+var ErrNotApproved = errors.New("plan was not approved")
+
+// Lifecycle plans, applies and destroys a scaffolded service. Changes are
+// only ever applied from a saved plan file.
+type Lifecycle struct {
+	Service *Service
+	// PlanFile is the saved plan, relative to the service directory.
+	PlanFile string
+	// AutoApprove skips the confirmation prompt before applying.
+	AutoApprove bool
+	// Environment selects the variable file and state of one of the
+	// service's environments. It is required for services that have any.
+	Environment string
+
+	In  io.Reader
+	Out io.Writer
+}
+
+// NewLifecycle creates a Lifecycle for service that prompts on stdin.
+func NewLifecycle(service *Service) *Lifecycle {
+	return &Lifecycle{
+		Service:  service,
+		PlanFile: defaultPlanFile,
+		In:       os.Stdin,
+		Out:      os.Stdout,
+	}
+}
+
+// ResourceChange is a single resource change of a plan.
+type ResourceChange struct {
+	Address string
+	Actions []string
+}
+
+// PlanSummary counts the changes of a saved plan.
+type PlanSummary struct {
+	Add     int
+	Change  int
+	Destroy int
+	Changes []ResourceChange
+}
+
+// HasChanges reports whether applying the plan changes anything.
+func (p *PlanSummary) HasChanges() bool {
+	return p.Add+p.Change+p.Destroy > 0
+}
+
+func (p *PlanSummary) String() string {
+	var sb strings.Builder
+	for _, change := range p.Changes {
+		fmt.Fprintf(&sb, "  %s %s\n", actionSymbol(change.Actions), change.Address)
+	}
+	fmt.Fprintf(&sb, "Plan: %d to add, %d to change, %d to destroy.\n", p.Add, p.Change, p.Destroy)
+	return sb.String()
+}
+
+// Plan saves a plan for the service, a destroy plan when destroy is set,
+// and returns its summary.
+func (l *Lifecycle) Plan(ctx context.Context, destroy bool) (*PlanSummary, error) {
+	if err := l.selectEnvironment(ctx); err != nil {
+		return nil, err
+	}
+	args := []string{"plan", "-input=false", "-no-color", "-out=" + l.PlanFile}
+	if l.Environment != "" {
+		args = append(args, "-var-file="+envVarFile(l.Environment))
+	}
+	if destroy {
+		args = append(args, "-destroy")
+	}
+	if _, err := l.Service.terraform().Run(ctx, l.Service.Dir(), args...); err != nil {
+		return nil, err
+	}
+	summary, err := l.Show(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprint(l.Out, summary)
+	return summary, nil
+}
+
+// Show summarises the saved plan from terraform show -json.
+func (l *Lifecycle) Show(ctx context.Context) (*PlanSummary, error) {
+	if _, err := os.Stat(filepath.Join(l.Service.Dir(), l.PlanFile)); err != nil {
+		return nil, fmt.Errorf("no saved plan, run plan first: %w", err)
+	}
+	output, err := l.Service.terraform().Output(ctx, l.Service.Dir(), "show", "-json", "-no-color", l.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlan(output)
+}
+
+// Apply applies the saved plan after showing its summary and, unless
+// AutoApprove is set, asking for confirmation. The plan file is removed
+// once applied since terraform refuses to apply it twice.
+func (l *Lifecycle) Apply(ctx context.Context) error {
+	summary, err := l.Show(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(l.Out, summary)
+	return l.apply(ctx, summary)
+}
+
+// apply applies the saved plan whose summary was already shown.
+func (l *Lifecycle) apply(ctx context.Context, summary *PlanSummary) error {
+	if !summary.HasChanges() {
+		fmt.Fprintln(l.Out, "No changes to apply.")
+		return nil
+	}
+	if !l.AutoApprove && !l.confirm() {
+		return ErrNotApproved
+	}
+	if err := l.selectEnvironment(ctx); err != nil {
+		return err
+	}
+
+	args := []string{"apply", "-input=false", "-no-color", l.PlanFile}
+	if _, err := l.Service.terraform().Run(ctx, l.Service.Dir(), args...); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(l.Service.Dir(), l.PlanFile))
+}
+
+// Destroy saves a destroy plan and applies it.
+func (l *Lifecycle) Destroy(ctx context.Context) error {
+	summary, err := l.Plan(ctx, true)
+	if err != nil {
+		return err
+	}
+	return l.apply(ctx, summary)
+}
+
+// selectEnvironment points the service's working directory at the state of
+// the lifecycle's environment. Every environment shares the directory, so
+// the backend is reconfigured before each command rather than trusted.
+func (l *Lifecycle) selectEnvironment(ctx context.Context) error {
+	if l.Environment == "" {
+		if _, err := os.Stat(filepath.Join(l.Service.Dir(), envDir)); err == nil {
+			return fmt.Errorf("%s has environments, select one to target", l.Service.Name)
+		}
+		return nil
+	}
+	if err := validateEnvironmentName(l.Environment); err != nil {
+		return fmt.Errorf("environment %w", err)
+	}
+	for _, file := range []string{envVarFile(l.Environment), envBackendFile(l.Environment)} {
+		if _, err := os.Stat(filepath.Join(l.Service.Dir(), file)); err != nil {
+			return fmt.Errorf("environment %q is not configured for %s: %w", l.Environment, l.Service.Name, err)
+		}
+	}
+	return l.Service.terraform().Init(ctx, l.Service.Dir(), "-reconfigure", "-backend-config="+envBackendFile(l.Environment))
+}
+
+// confirm asks the operator to approve the plan.
+func (l *Lifecycle) confirm() bool {
+	target := l.Service.Name
+	if l.Environment != "" {
+		target += " in " + l.Environment
+	}
+	fmt.Fprintf(l.Out, "Apply the plan to %s? Only 'yes' will be accepted: ", target)
+	answer, _ := bufio.NewReader(l.In).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// parsePlan counts the resource changes of a plan rendered as JSON. A
+// replacement counts as both an add and a destroy, as terraform reports it.
+func parsePlan(output []byte) (*PlanSummary, error) {
+	var plan struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(output, &plan); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+
+	summary := &PlanSummary{}
+	for _, rc := range plan.ResourceChanges {
+		actions := rc.Change.Actions
+		switch {
+		case slices.Contains(actions, "create") && slices.Contains(actions, "delete"):
+			summary.Add++
+			summary.Destroy++
+		case slices.Contains(actions, "create"):
+			summary.Add++
+		case slices.Contains(actions, "update"):
+			summary.Change++
+		case slices.Contains(actions, "delete"):
+			summary.Destroy++
+		default:
+			continue
+		}
+		summary.Changes = append(summary.Changes, ResourceChange{Address: rc.Address, Actions: actions})
+	}
+	return summary, nil
+}
+
+// actionSymbol renders actions the way terraform plan does.
+func actionSymbol(actions []string) string {
+	switch strings.Join(actions, ",") {
+	case "create":
+		return "+"
+	case "update":
+		return "~"
+	case "delete":
+		return "-"
+	case "delete,create":
+		return "-/+"
+	case "create,delete":
+		return "+/-"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePlanTerraform saves a plan on "plan", renders planJSON on "show" and
+// records the arguments of "init", "plan" and "apply".
+func fakePlanTerraform(t *testing.T, planJSON string) *TerraformRunner {
+	t.Helper()
+	script := `dir="${1#-chdir=}"
+case "$2" in
+  init) echo "$@" > "$dir/init.args" ;;
+  plan) echo "$@" > "$dir/plan.args"; touch "$dir/tfplan" ;;
+  show) cat <<'JSON'
+` + planJSON + `
+JSON
+  ;;
+  apply) echo "$@" > "$dir/apply.args" ;;
+esac`
+	return fakeTerraform(t, script)
+}
+
+const createPlanJSON = `{"resource_changes":[
+  {"address":"helm_release.web","change":{"actions":["create"]}},
+  {"address":"kubernetes_namespace.web","change":{"actions":["delete","create"]}},
+  {"address":"kubernetes_resource_quota.web","change":{"actions":["update"]}},
+  {"address":"data.vault_kv_secret_v2.web","change":{"actions":["read"]}},
+  {"address":"kubernetes_limit_range.web","change":{"actions":["no-op"]}}
+]}`
+
+func newTestLifecycle(t *testing.T, runner *TerraformRunner, answer string) (*Lifecycle, *bytes.Buffer) {
+	t.Helper()
+	service := NewService("web", t.TempDir(), "", "")
+	service.Runner = runner
+	if err := os.MkdirAll(service.Dir(), 0755); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	var out bytes.Buffer
+	lifecycle := NewLifecycle(service)
+	lifecycle.In = strings.NewReader(answer)
+	lifecycle.Out = &out
+	return lifecycle, &out
+}
+
+func TestParsePlan(t *testing.T) {
+	summary, err := parsePlan([]byte(createPlanJSON))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if summary.Add != 2 || summary.Change != 1 || summary.Destroy != 1 {
+		t.Errorf("expected 2 to add, 1 to change, 1 to destroy, got %+v", summary)
+	}
+	expected := `  + helm_release.web
+  -/+ kubernetes_namespace.web
+  ~ kubernetes_resource_quota.web
+Plan: 2 to add, 1 to change, 1 to destroy.
+`
+	if summary.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, summary.String())
+	}
+}
+
+func TestPlan(t *testing.T) {
+	lifecycle, out := newTestLifecycle(t, fakePlanTerraform(t, createPlanJSON), "")
+
+	if _, err := lifecycle.Plan(context.Background(), false); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(lifecycle.Service.Dir(), "plan.args"))
+	if !strings.Contains(string(args), "plan -input=false -no-color -out=tfplan") {
+		t.Errorf("unexpected plan arguments '%s'", args)
+	}
+	if !strings.Contains(out.String(), "Plan: 2 to add, 1 to change, 1 to destroy.") {
+		t.Errorf("expected a plan summary, got:\n%s", out.String())
+	}
+}
+
+func TestApplyRequiresSavedPlan(t *testing.T) {
+	lifecycle, _ := newTestLifecycle(t, fakePlanTerraform(t, createPlanJSON), "yes\n")
+
+	if err := lifecycle.Apply(context.Background()); err == nil {
+		t.Errorf("expected an error without a saved plan")
+	}
+	if _, err := os.Stat(filepath.Join(lifecycle.Service.Dir(), "apply.args")); err == nil {
+		t.Errorf("expected terraform apply not to run")
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		answer      string
+		autoApprove bool
+		applied     bool
+	}{
+		{"approved", "yes\n", false, true},
+		{"declined", "no\n", false, false},
+		{"auto approved", "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lifecycle, _ := newTestLifecycle(t, fakePlanTerraform(t, createPlanJSON), tt.answer)
+			lifecycle.AutoApprove = tt.autoApprove
+			if _, err := lifecycle.Plan(context.Background(), false); err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+
+			err := lifecycle.Apply(context.Background())
+			if tt.applied && err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			if !tt.applied && !errors.Is(err, ErrNotApproved) {
+				t.Fatalf("expected ErrNotApproved, got '%v'", err)
+			}
+
+			args, err := os.ReadFile(filepath.Join(lifecycle.Service.Dir(), "apply.args"))
+			if tt.applied != (err == nil) {
+				t.Fatalf("expected apply to run: %v, got '%v'", tt.applied, err)
+			}
+			if tt.applied && !strings.HasSuffix(strings.TrimSpace(string(args)), "apply -input=false -no-color tfplan") {
+				t.Errorf("expected apply from the saved plan, got '%s'", args)
+			}
+		})
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	lifecycle, out := newTestLifecycle(t, fakePlanTerraform(t, `{"resource_changes":[
+  {"address":"helm_release.web","change":{"actions":["delete"]}}
+]}`), "yes\n")
+
+	if err := lifecycle.Destroy(context.Background()); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(lifecycle.Service.Dir(), "plan.args"))
+	if !strings.Contains(string(args), "-destroy") {
+		t.Errorf("expected a destroy plan, got '%s'", args)
+	}
+	if _, err := os.Stat(filepath.Join(lifecycle.Service.Dir(), "apply.args")); err != nil {
+		t.Errorf("expected the destroy plan to be applied, got '%v'", err)
+	}
+	if count := strings.Count(out.String(), "Plan: "); count != 1 {
+		t.Errorf("expected the plan summary once, got it %d times:\n%s", count, out)
+	}
+}
+
+func TestPlanInvalidEnvironment(t *testing.T) {
+	lifecycle, _ := newTestLifecycle(t, fakePlanTerraform(t, createPlanJSON), "")
+	lifecycle.Environment = "../prod"
+
+	_, err := lifecycle.Plan(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), `name "../prod" must be`) {
+		t.Fatalf("expected an invalid environment error, got '%v'", err)
+	}
+	if _, err := os.Stat(filepath.Join(lifecycle.Service.Dir(), "plan.args")); err == nil {
+		t.Errorf("expected terraform not to run")
+	}
+}
+
+func TestPlanEnvironment(t *testing.T) {
+	lifecycle, _ := newTestLifecycle(t, fakePlanTerraform(t, createPlanJSON), "")
+	lifecycle.Environment = "prod"
+
+	if _, err := lifecycle.Plan(context.Background(), false); err == nil {
+		t.Fatalf("expected an error for an environment that is not configured")
+	}
+
+	dir := lifecycle.Service.Dir()
+	for _, file := range []string{envVarFile("prod"), envBackendFile("prod")} {
+		if err := writeFile(filepath.Join(dir, file), ""); err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+	}
+	if _, err := lifecycle.Plan(context.Background(), false); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "init.args"))
+	if !strings.Contains(string(args), "init -input=false -no-color -reconfigure -backend-config=env/prod.backend.hcl") {
+		t.Errorf("unexpected init arguments '%s'", args)
+	}
+	args, _ = os.ReadFile(filepath.Join(dir, "plan.args"))
+	if !strings.Contains(string(args), "-var-file=env/prod.tfvars") {
+		t.Errorf("unexpected plan arguments '%s'", args)
+	}
+
+	lifecycle.Environment = ""
+	if _, err := lifecycle.Plan(context.Background(), false); err == nil {
+		t.Errorf("expected an error when no environment is selected")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lockFile is the dependency lock file terraform providers lock writes.
+const lockFile = ".terraform.lock.hcl"
+
+// ParsePlatforms splits a comma-separated list of platforms such as
+// "linux_amd64,darwin_arm64".
+func ParsePlatforms(list string) ([]string, error) {
+	var platforms []string
+	for _, platform := range strings.Split(list, ",") {
+		if platform = strings.TrimSpace(platform); platform == "" {
+			continue
+		}
+		if !platformRe.MatchString(platform) {
+			return nil, fmt.Errorf("invalid platform %q, expected <os>_<arch>, e.g. linux_amd64", platform)
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms, nil
+}
+
+// MirrorProviders downloads the providers the service requires for every
+// platform into mirror, in the layout terraform providers lock reads.
+func (s *Service) MirrorProviders(ctx context.Context, mirror string, platforms []string) error {
+	args := []string{"providers", "mirror"}
+	for _, platform := range platforms {
+		args = append(args, "-platform="+platform)
+	}
+	_, err := s.terraform().Run(ctx, s.Dir(), append(args, mirror)...)
+	return err
+}
+
+// LockProviders writes the service's lock file with the checksums of its
+// providers for every platform, reading them from mirror instead of the
+// registry.
+func (s *Service) LockProviders(ctx context.Context, mirror string, platforms []string) error {
+	args := []string{"providers", "lock", "-fs-mirror=" + mirror}
+	for _, platform := range platforms {
+		args = append(args, "-platform="+platform)
+	}
+	_, err := s.terraform().Run(ctx, s.Dir(), args...)
+	return err
+}
+
+// bootstrappedServices returns the names of the services scaffolded under
+// path: the directories with a terraform.tf, except child modules.
+func bootstrappedServices(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == modulesDir {
+			continue
+		}
+		if fileExists(filepath.Join(path, entry.Name(), "terraform.tf")) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// defaultProviderMirror is the directory terraform searches for providers
+// on Linux and macOS before the registry.
+func defaultProviderMirror() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".terraform.d", "plugins")
+}
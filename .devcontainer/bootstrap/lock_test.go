@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := ParsePlatforms(" linux_amd64, darwin_arm64,")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if !reflect.DeepEqual(platforms, []string{"linux_amd64", "darwin_arm64"}) {
+		t.Errorf("unexpected platforms %v", platforms)
+	}
+	if _, err := ParsePlatforms("linux/amd64"); err == nil {
+		t.Errorf("expected an error for an invalid platform")
+	}
+}
+
+func TestLockProviders(t *testing.T) {
+	runner := fakeTerraform(t, `dir="${1#-chdir=}"; shift; echo "$@" >> "$dir/terraform.args"`)
+	service := NewService("web", t.TempDir(), "", "")
+	service.Runner = runner
+	if err := os.MkdirAll(service.Dir(), 0755); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	platforms := []string{"linux_amd64", "darwin_arm64"}
+	if err := service.MirrorProviders(context.Background(), "/mirror", platforms); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.LockProviders(context.Background(), "/mirror", platforms); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(service.Dir(), "terraform.args"))
+	expected := "providers mirror -platform=linux_amd64 -platform=darwin_arm64 /mirror\n" +
+		"providers lock -fs-mirror=/mirror -platform=linux_amd64 -platform=darwin_arm64\n"
+	if string(args) != expected {
+		t.Errorf("expected arguments:\n%s\ngot:\n%s", expected, args)
+	}
+}
+
+func TestBootstrappedServices(t *testing.T) {
+	path := t.TempDir()
+	for _, dir := range []string{"web", "api", modulesDir + "/web", "notes"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), 0755); err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+	}
+	for _, file := range []string{"web/terraform.tf", "api/terraform.tf", modulesDir + "/web/versions.tf"} {
+		if err := os.WriteFile(filepath.Join(path, file), nil, 0644); err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+	}
+
+	names, err := bootstrappedServices(path)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if strings.Join(names, ",") != "api,web" {
+		t.Errorf("expected api and web, got %v", names)
+	}
+}
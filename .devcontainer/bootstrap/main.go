@@ -0,0 +1,565 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultKubeConfigPath = "~/.kube/config"
+	defaultChartRepo      = "https://charts.bitnami.com/bitnami"
+	defaultPath           = "helms"
+)
+
+var once sync.Once
+
+func init() {
+	once.Do(func() {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		if os.Getenv("DEBUG") != "" {
+			zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		}
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: zerolog.TimeFormatUnix})
+	})
+}
+
+// serviceOnlyFlags describe a single service and cannot be combined with
+// -manifest, which describes every service itself.
+var serviceOnlyFlags = []string{"chart", "repository", "chart-version", "set", "vault-mount", "vault-path", "secret"}
+
+// visitedFlags returns the names among names of the flags set on the
+// command line, each prefixed with "-".
+func visitedFlags(names ...string) []string {
+	var visited []string
+	flag.Visit(func(f *flag.Flag) {
+		if slices.Contains(names, f.Name) {
+			visited = append(visited, "-"+f.Name)
+		}
+	})
+	return visited
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch command := os.Args[1]; command {
+		case "plan", "apply", "destroy":
+			if err := runLifecycle(command, os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "lock":
+			if err := runLock(os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	serviceName := flag.String("service", "", "Service name (required unless -manifest is set)")
+	manifestPath := flag.String("manifest", "", "Manifest listing the services to bootstrap")
+	path := flag.String("path", defaultPath, "Path name")
+	kubeConfig := flag.String("kubeconfig", defaultKubeConfig(), "Kubeconfig, or several separated as in KUBECONFIG")
+	kubeContext := flag.String("context", "", "Kubeconfig context (defaults to the current context)")
+	checkContext := flag.Bool("check-context", true, "Check that the kubeconfig defines the context before generating files")
+	chartName := flag.String("chart", "", "Chart name (defaults to the service name)")
+	chartRepo := flag.String("repository", defaultChartRepo, "Chart repository URL")
+	chartVersion := flag.String("chart-version", "", "Chart version or semver constraint (defaults to the latest stable version)")
+	resolve := flag.Bool("resolve", true, "Resolve the chart version from the repository index")
+	plainHTTP := flag.Bool("plain-http", false, "Reach OCI registries over HTTP instead of HTTPS, e.g. for a local registry")
+	chartValues := flag.Bool("chart-values", true, "Write the chart's default values to values/<service>.yaml (requires -resolve)")
+	set := keyValueFlag{}
+	flag.Var(set, "set", "Override a chart value as key=value, key= sets an empty string and key=null null (repeatable)")
+	environments := flag.String("environments", "", "Comma-separated environments as name or name=context, e.g. dev,prod=aks-prod")
+	kind := flag.String("kind", KindRoot, "Kind of module to generate: "+strings.Join(Kinds(), ", "))
+	featureList := flag.String("features", "", "Comma-separated guardrails to generate: "+strings.Join(FeatureNames(), ", ")+" or all")
+	vaultAddress := flag.String("vault-addr", envOr("VAULT_ADDR", defaultVaultAddress), "Vault address the release's secrets are read from")
+	vaultMount := flag.String("vault-mount", defaultVaultMount, "KV v2 mount of -vault-path")
+	vaultPath := flag.String("vault-path", "", "KV v2 secret whose keys are passed to the release with -secret")
+	secretSet := keyValueFlag{}
+	flag.Var(secretSet, "secret", "Set a chart value from a key of -vault-path as value=key (repeatable)")
+	policyPath := flag.String("provider-policy", "", "Provider pinning policy (defaults to the embedded policy)")
+	templateDir := flag.String("templates", "", "Directory containing a custom template set (defaults to the embedded set)")
+	backendKind := flag.String("backend", defaultBackend, "Terraform backend: "+strings.Join(BackendNames(), ", "))
+	backendConfig := keyValueFlag{}
+	flag.Var(backendConfig, "backend-config", "Backend parameter as key=value (repeatable)")
+	force := flag.Bool("force", false, "Overwrite files that differ from the generated content")
+	sidecar := flag.Bool("sidecar", false, "Write differing generated content to <file>.new instead of skipping it")
+	dryRun := flag.Bool("dry-run", false, "Print the generated files and diffs without writing anything")
+	terraformBinary := flag.String("terraform", envOr("TERRAFORM_BINARY", defaultTerraformBinary), "Terraform binary")
+	timeout := flag.Duration("timeout", defaultTerraformTimeout, "Timeout for each terraform command")
+	parallel := flag.Int("parallel", defaultParallelism, "Number of services bootstrapped at the same time")
+	pluginCache := flag.String("plugin-cache", envOr("TF_PLUGIN_CACHE_DIR", defaultPluginCacheDir()), "Provider cache shared by every service (empty to disable)")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if (*serviceName == "") == (*manifestPath == "") {
+		log.Error().Msg("Usage: bootstrap (-service <service-name> | -manifest <services.yaml>) -path <path> [-templates <dir>]")
+		os.Exit(1)
+	}
+
+	if *manifestPath != "" {
+		if names := visitedFlags(serviceOnlyFlags...); len(names) > 0 {
+			log.Error().Msgf("%s only apply to -service, set them in the manifest instead", strings.Join(names, ", "))
+			os.Exit(1)
+		}
+	}
+
+	if *force && *sidecar {
+		log.Error().Msg("-force and -sidecar are mutually exclusive")
+		os.Exit(1)
+	}
+	writer := &FileWriter{DryRun: *dryRun, Out: os.Stdout}
+	switch {
+	case *force:
+		writer.Policy = ConflictOverwrite
+	case *sidecar:
+		writer.Policy = ConflictSidecar
+	}
+
+	runner := NewTerraformRunner(*terraformBinary, *timeout)
+	if *pluginCache != "" && !*dryRun {
+		if err := os.MkdirAll(*pluginCache, 0755); err != nil {
+			log.Error().Err(err).Str("dir", *pluginCache).Msg("Error creating plugin cache")
+			os.Exit(1)
+		}
+		runner.PluginCacheDir = *pluginCache
+	}
+
+	templates, err := LoadTemplates(*templateDir)
+	if err != nil {
+		log.Error().Err(err).Str("dir", *templateDir).Msg("Error loading templates")
+		os.Exit(1)
+	}
+
+	policy, err := LoadProviderPolicy(*policyPath)
+	if err != nil {
+		log.Error().Err(err).Msg("Error loading provider policy")
+		os.Exit(1)
+	}
+
+	envs, err := ParseEnvironments(*environments)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid -environments")
+		os.Exit(1)
+	}
+
+	if err := validateKind(*kind); err != nil {
+		log.Error().Err(err).Msg("Invalid -kind")
+		os.Exit(1)
+	}
+
+	features, err := ParseFeatures(strings.Split(*featureList, ","))
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid -features")
+		os.Exit(1)
+	}
+
+	backend, err := NewBackend(*backendKind, backendConfig)
+	if err != nil {
+		log.Error().Err(err).Str("backend", *backendKind).Msg("Invalid backend configuration")
+		os.Exit(1)
+	}
+
+	var services []*Service
+	if *manifestPath != "" {
+		manifest, err := LoadManifest(*manifestPath)
+		if err != nil {
+			log.Error().Err(err).Msg("Error loading manifest")
+			os.Exit(1)
+		}
+		services = manifest.NewServices(*path, *kubeConfig, *kubeContext)
+	} else {
+		service := NewService(*serviceName, *path, *kubeConfig, *kubeContext)
+		service.Chart.Name = firstNonEmpty(*chartName, service.Chart.Name)
+		service.Chart.Repository = *chartRepo
+		service.Chart.Version = *chartVersion
+		service.Chart = service.Chart.splitOCI()
+		if len(set) > 0 {
+			service.Set = set
+		}
+		if *vaultPath != "" || len(secretSet) > 0 {
+			service.Secrets = []VaultSecret{{Mount: *vaultMount, Path: *vaultPath, Set: secretSet}}
+		}
+		services = []*Service{service}
+	}
+
+	valid := true
+	for _, service := range services {
+		if service.Environments == nil {
+			service.Environments = envs
+		}
+		if !service.Features.Kubernetes() {
+			service.Features = features
+		}
+		service.Kind = firstNonEmpty(service.Kind, *kind)
+		service.VaultAddress = firstNonEmpty(service.VaultAddress, *vaultAddress)
+		if err := service.Validate(); err != nil {
+			logValidation(service.Name, err)
+			valid = false
+		}
+	}
+	if !valid {
+		os.Exit(1)
+	}
+
+	kubeConfigs := NewKubeConfigs()
+	for _, service := range services {
+		if !*checkContext {
+			kubeConfigs.DefaultContexts(service)
+			continue
+		}
+		if err := kubeConfigs.ResolveService(service); err != nil {
+			log.Error().Err(err).Str("service", service.Name).Msg("Invalid kubeconfig context")
+			os.Exit(1)
+		}
+	}
+
+	if *resolve {
+		resolver := NewChartResolver()
+		// The generated configuration reads the same variables.
+		resolver.Username = os.Getenv("TF_VAR_repository_username")
+		resolver.Password = os.Getenv("TF_VAR_repository_password")
+		resolver.PlainHTTP = *plainHTTP
+		for _, service := range services {
+			if err := resolveChart(resolver, service, *chartValues); err != nil {
+				os.Exit(1)
+			}
+		}
+	}
+
+	for _, service := range services {
+		service.Templates = templates
+		service.Policy = policy
+		service.Backend = backend
+		service.Writer = writer
+		service.Runner = runner
+
+		// Without the chart's defaults the overrides still get a values file.
+		if service.ChartValues == nil && len(service.Set) > 0 {
+			if service.ChartValues, err = applyOverrides(nil, service.Set); err != nil {
+				log.Error().Err(err).Str("service", service.Name).Msg("Error applying values overrides")
+				os.Exit(1)
+			}
+		}
+	}
+
+	results := BootstrapAll(ctx, services, *parallel)
+	if len(results) > 1 {
+		printSummary(os.Stdout, results)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// runLifecycle runs the plan, apply or destroy subcommand against an already
+// scaffolded service. Failures are logged before they are returned.
+func runLifecycle(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	serviceName := flags.String("service", "", "Service name (required)")
+	path := flags.String("path", defaultPath, "Path name")
+	planFile := flags.String("plan", defaultPlanFile, "Saved plan file, relative to the service directory")
+	autoApprove := flags.Bool("auto-approve", false, "Apply without asking for confirmation")
+	env := flags.String("env", "", "Environment to target (required for services bootstrapped with -environments)")
+	wait := flags.Bool("wait", false, "After apply, wait for the release's Deployments and StatefulSets to roll out")
+	waitTimeout := flags.Duration("wait-timeout", defaultReadinessTimeout, "Deadline for -wait")
+	waitInterval := flags.Duration("wait-interval", defaultReadinessInterval, "Time between readiness checks")
+	healthURL := flags.String("health-url", "", "With -wait, URL that must answer 200 OK once the release is rolled out")
+	terraformBinary := flags.String("terraform", envOr("TERRAFORM_BINARY", defaultTerraformBinary), "Terraform binary")
+	timeout := flags.Duration("timeout", defaultTerraformTimeout, "Timeout for each terraform command")
+	flags.Parse(args)
+
+	if *serviceName == "" {
+		err := fmt.Errorf("-service is required")
+		log.Error().Err(err).Msgf("Usage: bootstrap %s -service <service-name> -path <path>", command)
+		return err
+	}
+
+	if err := validateReleaseName("service", *serviceName); err != nil {
+		logValidation(*serviceName, err)
+		return err
+	}
+
+	if *env != "" {
+		if err := validateEnvironmentName(*env); err != nil {
+			log.Error().Err(err).Str("service", *serviceName).Msg("Invalid -env")
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	service := NewService(*serviceName, *path, "", "")
+	service.Runner = NewTerraformRunner(*terraformBinary, *timeout)
+	if _, err := os.Stat(service.Dir()); err != nil {
+		log.Error().Err(err).Str("service", service.Name).Msg("Service has not been bootstrapped")
+		return err
+	}
+
+	lifecycle := NewLifecycle(service)
+	lifecycle.PlanFile = *planFile
+	lifecycle.AutoApprove = *autoApprove
+	lifecycle.Environment = *env
+	// Keep the saved plans of different environments apart.
+	if *env != "" && *planFile == defaultPlanFile {
+		lifecycle.PlanFile = defaultPlanFile + "-" + *env
+	}
+
+	var err error
+	switch command {
+	case "plan":
+		_, err = lifecycle.Plan(ctx, false)
+	case "apply":
+		err = lifecycle.Apply(ctx)
+	case "destroy":
+		err = lifecycle.Destroy(ctx)
+	}
+	if err == nil && command == "apply" && *wait {
+		err = waitReady(ctx, service, *env, *healthURL, *waitTimeout, *waitInterval)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("service", service.Name).Str("env", *env).Msgf("Error running %s", command)
+		return err
+	}
+	return nil
+}
+
+// runLock runs the lock subcommand: it records the checksums of the
+// providers of one or every bootstrapped service for several platforms,
+// reading the providers from a local mirror. Failures are logged before
+// they are returned.
+func runLock(args []string) error {
+	flags := flag.NewFlagSet("lock", flag.ExitOnError)
+	serviceName := flags.String("service", "", "Service name (defaults to every service under -path)")
+	path := flags.String("path", defaultPath, "Path name")
+	policyPath := flags.String("provider-policy", "", "Provider pinning policy (defaults to the embedded policy)")
+	platformList := flags.String("platforms", "", "Comma-separated platforms to lock, e.g. linux_amd64,darwin_arm64 (defaults to the policy's)")
+	mirror := flags.String("mirror", envOr("TF_PROVIDER_MIRROR", defaultProviderMirror()), "Local provider mirror to read providers from")
+	fetch := flags.Bool("fetch", false, "Download the providers of every platform into -mirror first")
+	terraformBinary := flags.String("terraform", envOr("TERRAFORM_BINARY", defaultTerraformBinary), "Terraform binary")
+	timeout := flags.Duration("timeout", defaultTerraformTimeout, "Timeout for each terraform command")
+	flags.Parse(args)
+
+	policy, err := LoadProviderPolicy(*policyPath)
+	if err != nil {
+		log.Error().Err(err).Msg("Error loading provider policy")
+		return err
+	}
+	platforms, err := ParsePlatforms(*platformList)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid -platforms")
+		return err
+	}
+	if len(platforms) == 0 {
+		platforms = policy.Platforms
+	}
+	if len(platforms) == 0 {
+		err := fmt.Errorf("no platforms to lock")
+		log.Error().Err(err).Msg("Pass -platforms or list platforms in the provider policy")
+		return err
+	}
+	// terraform runs in the service directory.
+	mirrorDir, err := filepath.Abs(expandPath(*mirror))
+	if err != nil {
+		log.Error().Err(err).Str("mirror", *mirror).Msg("Invalid -mirror")
+		return err
+	}
+
+	names := []string{*serviceName}
+	if *serviceName == "" {
+		if names, err = bootstrappedServices(*path); err != nil {
+			log.Error().Err(err).Str("path", *path).Msg("Error listing services")
+			return err
+		}
+		if len(names) == 0 {
+			err := fmt.Errorf("no services found under %s", *path)
+			log.Error().Err(err).Msg("Nothing to lock")
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := NewTerraformRunner(*terraformBinary, *timeout)
+	for _, name := range names {
+		if err := validateReleaseName("service", name); err != nil {
+			logValidation(name, err)
+			return err
+		}
+		service := NewService(name, *path, "", "")
+		service.Runner = runner
+		logger := log.With().Str("service", name).Logger()
+		if _, err := os.Stat(service.Dir()); err != nil {
+			logger.Error().Err(err).Msg("Service has not been bootstrapped")
+			return err
+		}
+		if *fetch {
+			if err := service.MirrorProviders(ctx, mirrorDir, platforms); err != nil {
+				logger.Error().Err(err).Str("mirror", mirrorDir).Msg("Error mirroring providers")
+				return err
+			}
+		}
+		if err := service.LockProviders(ctx, mirrorDir, platforms); err != nil {
+			logger.Error().Err(err).Str("mirror", mirrorDir).Msg("Error locking providers")
+			return err
+		}
+		logger.Info().Str("file", filepath.Join(service.Dir(), lockFile)).Strs("platforms", platforms).Msg("Locked providers")
+	}
+	return nil
+}
+
+// waitReady waits for the release of an applied service to become ready.
+func waitReady(ctx context.Context, service *Service, env, healthURL string, timeout, interval time.Duration) error {
+	gate, err := service.ReadinessGate(env)
+	if err != nil {
+		return err
+	}
+	gate.HealthURL = healthURL
+	gate.Timeout = timeout
+	gate.Interval = interval
+	return gate.Wait(ctx)
+}
+
+// resolveChart pins the service's chart version and, when fetchValues is
+// set, loads the chart's default values with the service's overrides
+// applied. Failures are logged before they are returned.
+func resolveChart(resolver *ChartResolver, service *Service, fetchValues bool) error {
+	logger := log.With().Str("service", service.Name).Logger()
+
+	chart, err := resolver.Resolve(service.Chart)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error resolving chart")
+		return err
+	}
+	logger.Info().Str("chart", chart.Name).Str("version", chart.Version).Msg("Resolved chart")
+	service.Chart = chart
+
+	if !fetchValues {
+		return nil
+	}
+	values, err := resolver.FetchValues(chart)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error fetching chart values")
+		return err
+	}
+	service.ChartValues, err = applyOverrides(values, service.Set)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error applying values overrides")
+		return err
+	}
+	return nil
+}
+
+// bootstrapService scaffolds the service directory and initializes Terraform
+// in it. Failures are logged before they are returned.
+func bootstrapService(ctx context.Context, service *Service) error {
+	logger := log.With().Str("service", service.Name).Logger()
+
+	err := service.CreateDirectory()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating directory")
+		return err
+	}
+
+	err = service.CreateTerraformFiles()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating Terraform files")
+		return err
+	}
+
+	err = service.CreateValuesFiles()
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating values files")
+		return err
+	}
+
+	if service.writer().DryRun {
+		return nil
+	}
+
+	err = service.InitializeTerraform(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error initializing Terraform")
+		return err
+	}
+	return nil
+}
+
+// logValidation logs every problem reported by a failed validation, with
+// the suggested value when there is one.
+func logValidation(service string, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		event := log.Error().Str("service", service)
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			event.Err(err).Msg("Invalid service")
+			continue
+		}
+		event = event.Str("field", invalid.Field).Str("value", invalid.Value)
+		if invalid.Suggestion != "" {
+			event = event.Str("suggestion", invalid.Suggestion)
+		}
+		event.Msgf("Invalid %s: %s", invalid.Field, invalid.Reason)
+	}
+}
+
+// keyValueFlag collects repeated key=value flags into a map.
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[k] = v
+	return nil
+}
+
+// defaultPluginCacheDir is the plugin cache directory terraform's
+// documentation suggests, or empty when there is no home directory.
+func defaultPluginCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".terraform.d", "plugin-cache")
+}
+
+// envOr returns the environment variable key, or fallback when it is unset.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
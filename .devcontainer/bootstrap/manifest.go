@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is a declarative list of services to bootstrap.
+//
+//	defaults:
+//	  context: docker-desktop
+//	services:
+//	  - name: web
+//	    namespace: frontend
+//	    kind: module
+//	    chart:
+//	      name: nginx
+//	      repository: https://charts.bitnami.com/bitnami
+//	      version: 13.0.0
+//	    values:
+//	      - values/web.yaml
+//	    set:
+//	      replicaCount: "2"
+//	    environments:
+//	      - name: dev
+//	        context: kind-dev
+//	      - name: prod
+//	        context: aks-prod
+//	    features: [namespace, resource-quota, limit-range, network-policy]
+//	    secrets:
+//	      - path: web/credentials
+//	        set:
+//	          auth.password: password
+type Manifest struct {
+	Defaults ManifestService   `yaml:"defaults"`
+	Services []ManifestService `yaml:"services"`
+
+	// dir is the directory of the manifest file; values files are resolved
+	// relative to it.
+	dir string
+}
+
+// ManifestService is a single service entry of a manifest. Empty fields
+// fall back to the manifest defaults and then to the bootstrap defaults.
+type ManifestService struct {
+	Name       string            `yaml:"name"`
+	Namespace  string            `yaml:"namespace"`
+	KubeConfig string            `yaml:"kubeconfig"`
+	Context    string            `yaml:"context"`
+	Chart      Chart             `yaml:"chart"`
+	Values     []string          `yaml:"values"`
+	Set        map[string]string `yaml:"set"`
+
+	// Kind is the kind of module generated, see -kind.
+	Kind         string        `yaml:"kind"`
+	Environments []Environment `yaml:"environments"`
+	Features     []string      `yaml:"features"`
+
+	Secrets      []VaultSecret `yaml:"secrets"`
+	VaultAddress string        `yaml:"vault_address"`
+}
+
+// LoadManifest reads and validates the manifest at path.
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	m.dir = filepath.Dir(path)
+
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	if len(m.Services) == 0 {
+		return fmt.Errorf("no services defined")
+	}
+	if err := validateValues(m.Defaults.Values); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	if err := validateKind(m.Defaults.Kind); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	if err := validateEnvironments(m.Defaults.Environments); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	if _, err := ParseFeatures(m.Defaults.Features); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	if err := validateSecrets(m.Defaults.Secrets); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	seen := make(map[string]bool, len(m.Services))
+	for i, entry := range m.Services {
+		if entry.Name == "" {
+			return fmt.Errorf("services[%d]: name is required", i)
+		}
+		if seen[entry.Name] {
+			return fmt.Errorf("services[%d]: duplicate service %q", i, entry.Name)
+		}
+		seen[entry.Name] = true
+
+		if err := validateValues(entry.Values); err != nil {
+			return fmt.Errorf("services[%d]: %w", i, err)
+		}
+		if err := validateKind(entry.Kind); err != nil {
+			return fmt.Errorf("services[%d]: %w", i, err)
+		}
+		if err := validateEnvironments(entry.Environments); err != nil {
+			return fmt.Errorf("services[%d]: %w", i, err)
+		}
+		if _, err := ParseFeatures(entry.Features); err != nil {
+			return fmt.Errorf("services[%d]: %w", i, err)
+		}
+		if err := validateSecrets(entry.Secrets); err != nil {
+			return fmt.Errorf("services[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateValues rejects values files that would be copied over each other.
+func validateValues(values []string) error {
+	bases := make(map[string]bool, len(values))
+	for _, v := range values {
+		base := filepath.Base(v)
+		if bases[base] {
+			return fmt.Errorf("more than one values file named %q", base)
+		}
+		bases[base] = true
+	}
+	return nil
+}
+
+// NewServices builds a Service for every manifest entry, scaffolded under
+// path. kubeConfig and context apply to entries that the manifest does not
+// give either of.
+func (m *Manifest) NewServices(path, kubeConfig, context string) []*Service {
+	services := make([]*Service, 0, len(m.Services))
+	for _, entry := range m.Services {
+		service := NewService(entry.Name, path,
+			firstNonEmpty(entry.KubeConfig, m.Defaults.KubeConfig, kubeConfig),
+			firstNonEmpty(entry.Context, m.Defaults.Context, context),
+		)
+		service.Namespace = firstNonEmpty(entry.Namespace, m.Defaults.Namespace, service.Namespace)
+		service.Chart = Chart{
+			Name:       firstNonEmpty(entry.Chart.Name, m.Defaults.Chart.Name, service.Chart.Name),
+			Repository: firstNonEmpty(entry.Chart.Repository, m.Defaults.Chart.Repository, service.Chart.Repository),
+			Version:    firstNonEmpty(entry.Chart.Version, m.Defaults.Chart.Version, service.Chart.Version),
+		}.splitOCI()
+		values := entry.Values
+		if values == nil {
+			values = m.Defaults.Values
+		}
+		service.Kind = firstNonEmpty(entry.Kind, m.Defaults.Kind)
+		service.Environments = entry.Environments
+		if service.Environments == nil {
+			service.Environments = m.Defaults.Environments
+		}
+		features := entry.Features
+		if features == nil {
+			features = m.Defaults.Features
+		}
+		// Validated when the manifest was loaded.
+		service.Features, _ = ParseFeatures(features)
+		service.Secrets = entry.Secrets
+		if service.Secrets == nil {
+			service.Secrets = m.Defaults.Secrets
+		}
+		service.VaultAddress = firstNonEmpty(entry.VaultAddress, m.Defaults.VaultAddress)
+		if len(m.Defaults.Set)+len(entry.Set) > 0 {
+			service.Set = make(map[string]string, len(m.Defaults.Set)+len(entry.Set))
+			maps.Copy(service.Set, m.Defaults.Set)
+			maps.Copy(service.Set, entry.Set)
+		}
+		for _, v := range values {
+			if !filepath.IsAbs(v) {
+				v = filepath.Join(m.dir, v)
+			}
+			service.ValuesFiles = append(service.ValuesFiles, v)
+		}
+		services = append(services, service)
+	}
+	return services
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	manifest, err := LoadManifest(filepath.Join("testdata", "services.yaml"))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	services := manifest.NewServices("test-path", defaultKubeConfigPath, "")
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+
+	web := services[0]
+	if web.Name != "web" || web.Namespace != "frontend" {
+		t.Errorf("expected web in frontend, got '%s' in '%s'", web.Name, web.Namespace)
+	}
+	if web.Context != "kind-platform" || web.KubeConfig != defaultKubeConfigPath {
+		t.Errorf("expected defaults for kube settings, got '%s' and '%s'", web.KubeConfig, web.Context)
+	}
+	expectedChart := Chart{Name: "nginx", Repository: "https://charts.bitnami.com/bitnami", Version: "13.0.0"}
+	if web.Chart != expectedChart {
+		t.Errorf("expected chart %+v, got %+v", expectedChart, web.Chart)
+	}
+	if len(web.ValuesFiles) != 1 || web.ValuesFiles[0] != filepath.Join("testdata", "values", "web.yaml") {
+		t.Errorf("expected values file relative to the manifest, got %v", web.ValuesFiles)
+	}
+	if names := web.EnvironmentNames(); strings.Join(names, ",") != "dev,prod" {
+		t.Errorf("expected environments dev and prod, got %v", names)
+	}
+
+	cache := services[1]
+	if cache.Namespace != "cache" {
+		t.Errorf("expected namespace to default to the service name, got '%s'", cache.Namespace)
+	}
+	if cache.KubeConfig != "/etc/kube/config" || cache.Context != "kind-cache" {
+		t.Errorf("expected kube settings from the entry, got '%s' and '%s'", cache.KubeConfig, cache.Context)
+	}
+	if cache.Chart.Repository != "https://charts.example.com/stable" {
+		t.Errorf("expected repository from the entry, got '%s'", cache.Chart.Repository)
+	}
+}
+
+func TestLoadManifestValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"no services", "services: []"},
+		{"missing name", "services:\n  - namespace: x"},
+		{"duplicate name", "services:\n  - name: a\n  - name: a"},
+		{"unknown field", "services:\n  - name: a\n    chart_name: nginx"},
+		{"clashing values", "services:\n  - name: a\n    values: [a/values.yaml, b/values.yaml]"},
+		{"unknown feature", "services:\n  - name: a\n    features: [pod-security]"},
+		{"duplicate environment", "services:\n  - name: a\n    environments: [{name: dev}, {name: dev}]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "services.yaml")
+			if err := os.WriteFile(path, []byte(tt.manifest), 0644); err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			if _, err := LoadManifest(path); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestManifestServiceFiles(t *testing.T) {
+	manifest, err := LoadManifest(filepath.Join("testdata", "services.yaml"))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	path := t.TempDir()
+	web := manifest.NewServices(path, defaultKubeConfigPath, "")[0]
+
+	if err := web.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := web.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := web.CreateValuesFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	tfvars, err := os.ReadFile(filepath.Join(path, "web", "terraform.tfvars"))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, line := range []string{
+		`namespace      = "frontend"`,
+		`chart_name     = "nginx"`,
+		`chart_version  = "13.0.0"`,
+		`values_files   = ["values/web.yaml"]`,
+	} {
+		if !strings.Contains(string(tfvars), line) {
+			t.Errorf("expected %q in:\n%s", line, tfvars)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(path, "web", "values", "web.yaml")); err != nil {
+		t.Errorf("expected values file to be copied, got '%v'", err)
+	}
+}
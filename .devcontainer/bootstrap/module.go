@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// KindRoot scaffolds a root module that declares the release itself.
+	KindRoot = "root"
+	// KindModule scaffolds the release as a child module under modules/
+	// next to the service, and a thin root module calling it, the way
+	// gitlab/modules/pat is laid out.
+	KindModule = "module"
+
+	// modulesDir is the directory, relative to the services path, child
+	// modules are generated into.
+	modulesDir = "modules"
+)
+
+// Kinds returns the supported kinds of generated modules.
+func Kinds() []string {
+	return []string{KindRoot, KindModule}
+}
+
+// validateKind rejects kinds other than those returned by Kinds. An empty
+// kind means KindRoot.
+func validateKind(kind string) error {
+	switch kind {
+	case "", KindRoot, KindModule:
+		return nil
+	}
+	return fmt.Errorf("unknown kind %q, expected one of %s", kind, strings.Join(Kinds(), ", "))
+}
+
+// IsModule reports whether the release is generated as a child module.
+func (s *Service) IsModule() bool {
+	return s.Kind == KindModule
+}
+
+// ModuleDir returns the directory the service's child module is generated
+// into.
+func (s *Service) ModuleDir() string {
+	return filepath.Join(s.Path, modulesDir, s.Name)
+}
+
+// ModuleSource returns the source the root module calls the child module
+// with.
+func (s *Service) ModuleSource() string {
+	return path.Join("..", modulesDir, s.Name)
+}
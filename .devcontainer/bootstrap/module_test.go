@@ -0,0 +1,143 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateKind(t *testing.T) {
+	for _, kind := range []string{"", KindRoot, KindModule} {
+		if err := validateKind(kind); err != nil {
+			t.Errorf("expected kind %q to be valid, got '%v'", kind, err)
+		}
+	}
+	if err := validateKind("library"); err == nil {
+		t.Errorf("expected an error for an unknown kind")
+	}
+}
+
+func renderModuleFiles(t *testing.T, service *Service) map[string]string {
+	t.Helper()
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	contents := map[string]string{}
+	for _, file := range files {
+		rel, err := filepath.Rel(service.Path, file.path)
+		if err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+		contents[filepath.ToSlash(rel)] = file.content
+	}
+	return contents
+}
+
+func TestRootKindHasNoChildModule(t *testing.T) {
+	contents := renderModuleFiles(t, NewService("web", "test-path", "", ""))
+
+	for file := range contents {
+		if strings.HasPrefix(file, modulesDir+"/") {
+			t.Errorf("expected no child module files, got %s", file)
+		}
+	}
+	if !strings.Contains(contents["web/main.tf"], `resource "helm_release" "web"`) {
+		t.Errorf("expected the root module to declare the release, got:\n%s", contents["web/main.tf"])
+	}
+}
+
+func TestModuleKind(t *testing.T) {
+	service := NewService("web", "test-path", "", "")
+	service.Kind = KindModule
+	contents := renderModuleFiles(t, service)
+
+	expected := map[string][]string{
+		"web/main.tf": {
+			`module "web" {`,
+			`source = "../modules/web"`,
+			`values              = [for f in var.values_files : file("${path.module}/${f}")]`,
+		},
+		"web/variables.tf":         {`variable "kubeconfig" {`, `variable "values_files" {`},
+		"web/providers.tf":         {`provider "helm" {`},
+		"web/terraform.tf":         {`version = "2.17.0"`},
+		"modules/web/main.tf":      {`resource "helm_release" "web" {`, "values              = var.values"},
+		"modules/web/variables.tf": {`variable "release_name" {`, `variable "values" {`},
+		"modules/web/outputs.tf":   {`value       = helm_release.web.status`},
+		"modules/web/versions.tf":  {`version = "~> 2.17"`, `required_version = "~> 1.11"`},
+	}
+	for file, snippets := range expected {
+		content, ok := contents[file]
+		if !ok {
+			t.Errorf("expected %s to be generated", file)
+			continue
+		}
+		for _, snippet := range snippets {
+			if !strings.Contains(content, snippet) {
+				t.Errorf("expected %s to contain %q, got:\n%s", file, snippet, content)
+			}
+		}
+	}
+
+	if strings.Contains(contents["web/main.tf"], "helm_release") {
+		t.Errorf("expected the root module to only call the child module, got:\n%s", contents["web/main.tf"])
+	}
+	if strings.Contains(contents["modules/web/variables.tf"], "kubeconfig") {
+		t.Errorf("expected the child module to leave the cluster to its caller, got:\n%s", contents["modules/web/variables.tf"])
+	}
+	for _, file := range []string{"modules/web/providers.tf", "modules/web/backend.tf", "modules/web/terraform.tfvars"} {
+		if _, ok := contents[file]; ok {
+			t.Errorf("expected no %s in the child module", file)
+		}
+	}
+}
+
+func TestModuleKindWithFeaturesAndSecrets(t *testing.T) {
+	service := NewService("web", "test-path", "", "")
+	service.Kind = KindModule
+	service.Environments = []Environment{{Name: "dev"}}
+	service.Features = Features{Namespace: true, ResourceQuota: true, LimitRange: true}
+	service.Secrets = []VaultSecret{{Path: "web/credentials", Set: map[string]string{"auth.password": "password"}}}
+	contents := renderModuleFiles(t, service)
+
+	for _, file := range []string{"web/namespace.tf", "web/vault.tf"} {
+		if _, ok := contents[file]; ok {
+			t.Errorf("expected %s to move into the child module", file)
+		}
+	}
+	expected := map[string][]string{
+		"web/main.tf": {
+			"environment                = var.environment",
+			"namespace_labels           = var.namespace_labels",
+			"resource_quota             = var.resource_quota",
+			"container_default_limits   = var.container_default_limits",
+		},
+		"web/providers.tf":         {`provider "kubernetes" {`, `provider "vault" {`},
+		"modules/web/namespace.tf": {`resource "kubernetes_namespace" "this" {`, `"environment"                  = var.environment`},
+		"modules/web/vault.tf":     {`data "vault_kv_secret_v2" "secret_web_credentials" {`},
+		"modules/web/main.tf":      {"data.vault_kv_secret_v2.secret_web_credentials.data[\"password\"]"},
+		"modules/web/versions.tf":  {`source  = "hashicorp/kubernetes"`, `source  = "hashicorp/vault"`},
+		"modules/web/variables.tf": {`variable "environment" {`, `variable "resource_quota" {`},
+	}
+	for file, snippets := range expected {
+		for _, snippet := range snippets {
+			if !strings.Contains(contents[file], snippet) {
+				t.Errorf("expected %s to contain %q, got:\n%s", file, snippet, contents[file])
+			}
+		}
+	}
+}
+
+func TestHCLIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"web":    "web",
+		"web-ui": "web-ui",
+		"web.v2": "web_v2",
+		"2048":   "_2048",
+	}
+	for name, expected := range cases {
+		if got := hclIdentifier(name); got != expected {
+			t.Errorf("expected %q to become %q, got %q", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const ociScheme = "oci://"
+
+// linkNextRe extracts the next page from an RFC 5988 Link header.
+var linkNextRe = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// IsOCI reports whether the chart is stored in an OCI registry.
+func (c Chart) IsOCI() bool {
+	return strings.HasPrefix(c.Repository, ociScheme) || strings.HasPrefix(c.Name, ociScheme)
+}
+
+// splitOCI returns the chart with a full oci:// reference split into the
+// repository and chart name the helm provider expects, e.g.
+// oci://registry-1.docker.io/bitnamicharts/nginx becomes repository
+// oci://registry-1.docker.io/bitnamicharts and chart nginx. A version tag on
+// the reference is moved to Version unless one is already set.
+func (c Chart) splitOCI() Chart {
+	if !c.IsOCI() {
+		return c
+	}
+	ref := c.Name
+	if !strings.HasPrefix(ref, ociScheme) {
+		if c.Name != "" {
+			return c
+		}
+		ref = c.Repository
+	}
+	ref = strings.TrimSuffix(ref, "/")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		if c.Version == "" {
+			c.Version = ref[i+1:]
+		}
+		ref = ref[:i]
+	}
+	c.Repository, c.Name = path.Dir(strings.TrimPrefix(ref, ociScheme)), path.Base(ref)
+	c.Repository = ociScheme + c.Repository
+	return c
+}
+
+// ociReference splits an OCI chart into its registry host and repository path.
+func (c Chart) ociReference() (registry, repository string) {
+	ref := strings.TrimPrefix(c.Repository, ociScheme)
+	registry, namespace, _ := strings.Cut(ref, "/")
+	return registry, strings.Trim(path.Join(namespace, c.Name), "/")
+}
+
+// ListTags returns every tag of the chart through the OCI distribution API.
+// Helm stores the "+" of semver build metadata as "_" in tags, which is
+// converted back.
+func (r *ChartResolver) ListTags(chart Chart) ([]string, error) {
+	registry, repository := chart.ociReference()
+	next := fmt.Sprintf("%s://%s/v2/%s/tags/list", r.registryScheme(), registry, repository)
+
+	var tags []string
+	for next != "" {
+		resp, err := r.registryGet(next, repository, "application/json")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		link := resp.Header.Get("Link")
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing tags of %s: %w", repository, err)
+		}
+		for _, tag := range page.Tags {
+			tags = append(tags, strings.ReplaceAll(tag, "_", "+"))
+		}
+
+		next = ""
+		if m := linkNextRe.FindStringSubmatch(link); m != nil {
+			ref, err := resp.Request.URL.Parse(m[1])
+			if err != nil {
+				return nil, err
+			}
+			next = ref.String()
+		}
+	}
+	return tags, nil
+}
+
+// registryScheme returns the scheme the registry is reached with.
+func (r *ChartResolver) registryScheme() string {
+	if r.PlainHTTP {
+		return "http"
+	}
+	return "https"
+}
+
+// registryGet performs an authenticated GET against a registry. Anonymous
+// access is tried first; a bearer challenge is answered with a token, using
+// the resolver's credentials when it has any.
+func (r *ChartResolver) registryGet(target, repository, accept string) (*http.Response, error) {
+	resp, err := r.doRegistry(target, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		drain(resp)
+
+		authorization, err := r.authorize(challenge, repository)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = r.doRegistry(target, accept, authorization); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		drain(resp)
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", target, resp.Status)
+	}
+	return resp, nil
+}
+
+func (r *ChartResolver) doRegistry(target, accept, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return r.Client.Do(req)
+}
+
+// authorize answers a WWW-Authenticate challenge and returns the value of
+// the Authorization header to retry with.
+func (r *ChartResolver) authorize(challenge, repository string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.Username == "" {
+			return "", fmt.Errorf("registry requires credentials")
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(r.Username + ":" + r.Password))
+		return "Basic " + credentials, nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid registry token realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer drain(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching registry token: unexpected status %s", resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("parsing registry token: %w", err)
+	}
+	return "Bearer " + firstNonEmpty(token.Token, token.AccessToken), nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(rest, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+		if key != "" {
+			params[key] = value
+		}
+	}
+	return scheme, params
+}
+
+// drain discards the rest of the body so the connection can be reused.
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
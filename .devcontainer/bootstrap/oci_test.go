@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSplitOCI(t *testing.T) {
+	tests := []struct {
+		chart    Chart
+		expected Chart
+	}{
+		{
+			Chart{Name: "oci://registry-1.docker.io/bitnamicharts/nginx"},
+			Chart{Name: "nginx", Repository: "oci://registry-1.docker.io/bitnamicharts"},
+		},
+		{
+			Chart{Name: "oci://ghcr.io/org/charts/app:1.2.3", Repository: defaultChartRepo},
+			Chart{Name: "app", Repository: "oci://ghcr.io/org/charts", Version: "1.2.3"},
+		},
+		{
+			Chart{Repository: "oci://localhost:5000/charts/app"},
+			Chart{Name: "app", Repository: "oci://localhost:5000/charts"},
+		},
+		{
+			Chart{Name: "nginx", Repository: "oci://registry-1.docker.io/bitnamicharts"},
+			Chart{Name: "nginx", Repository: "oci://registry-1.docker.io/bitnamicharts"},
+		},
+		{
+			Chart{Name: "nginx", Repository: defaultChartRepo},
+			Chart{Name: "nginx", Repository: defaultChartRepo},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.chart.splitOCI(); got != tt.expected {
+			t.Errorf("expected %+v, got %+v", tt.expected, got)
+		}
+	}
+}
+
+// newRegistryServer serves the tags of charts/app in two pages behind a
+// bearer token challenge.
+func newRegistryServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:charts/app:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/charts/app/tags/list" && r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/charts/app/tags/list?last=1.1.0>; rel="next"`)
+			json.NewEncoder(w).Encode(map[string]any{"name": "charts/app", "tags": []string{"1.0.0", "1.1.0"}})
+		case r.URL.Path == "/v2/charts/app/tags/list":
+			json.NewEncoder(w).Encode(map[string]any{"name": "charts/app", "tags": []string{"2.0.0-beta.1", "1.2.0_build.5", "latest"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListTags(t *testing.T) {
+	server := newRegistryServer(t)
+	resolver := &ChartResolver{Client: server.Client()}
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	tags, err := resolver.ListTags(Chart{Name: "app", Repository: "oci://" + registry + "/charts"})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := "1.0.0,1.1.0,2.0.0-beta.1,1.2.0+build.5,latest"
+	if strings.Join(tags, ",") != expected {
+		t.Errorf("expected tags %s, got %v", expected, tags)
+	}
+}
+
+func TestResolveOCIChart(t *testing.T) {
+	server := newRegistryServer(t)
+	resolver := &ChartResolver{Client: server.Client()}
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	chart, err := resolver.Resolve(Chart{Name: "oci://" + registry + "/charts/app"})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := Chart{Name: "app", Repository: "oci://" + registry + "/charts", Version: "1.2.0+build.5"}
+	if chart != expected {
+		t.Errorf("expected %+v, got %+v", expected, chart)
+	}
+
+	if _, err := resolver.Resolve(Chart{Name: "missing", Repository: "oci://" + registry + "/charts"}); err == nil {
+		t.Errorf("expected an error for a missing chart")
+	}
+}
+
+func TestListTagsPlainHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"name": "charts/app", "tags": []string{"1.0.0"}})
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	chart := Chart{Name: "app", Repository: "oci://" + registry + "/charts"}
+
+	if _, err := (&ChartResolver{Client: server.Client()}).ListTags(chart); err == nil {
+		t.Errorf("expected HTTPS to fail against a plain HTTP registry")
+	}
+	tags, err := (&ChartResolver{Client: server.Client(), PlainHTTP: true}).ListTags(chart)
+	if err != nil || strings.Join(tags, ",") != "1.0.0" {
+		t.Errorf("expected the tags over plain HTTP, got %v, '%v'", tags, err)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:a/b:pull,push"`)
+	if scheme != "Bearer" {
+		t.Errorf("expected Bearer, got '%s'", scheme)
+	}
+	if params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:a/b:pull,push" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
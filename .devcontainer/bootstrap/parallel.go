@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultParallelism = 4
+
+// BootstrapResult is the outcome of bootstrapping a single service.
+type BootstrapResult struct {
+	Service  string
+	Err      error
+	Duration time.Duration
+}
+
+// Status summarises the result in a word.
+func (r BootstrapResult) Status() string {
+	switch {
+	case r.Err == nil:
+		return "ok"
+	case errors.Is(r.Err, context.Canceled):
+		return "cancelled"
+	default:
+		return "failed"
+	}
+}
+
+// BootstrapAll bootstraps services with at most concurrency of them running
+// at a time and returns their results in the order of services.
+//
+// Terraform output is logged line by line tagged with the service. With more
+// than one worker, the diffs of each service are buffered and printed in one
+// piece once it is done so they do not interleave. Services sharing a
+// plugin cache still run terraform init one at a time, see
+// TerraformRunner.Init.
+func BootstrapAll(ctx context.Context, services []*Service, concurrency int) []BootstrapResult {
+	results := make([]BootstrapResult, len(services))
+	if len(services) == 0 {
+		return results
+	}
+	concurrency = max(concurrency, 1)
+
+	var mu sync.Mutex // serialises flushing buffered output
+	run := func(i int) {
+		service := services[i]
+		if err := ctx.Err(); err != nil {
+			results[i] = BootstrapResult{Service: service.Name, Err: err}
+			return
+		}
+
+		var buf bytes.Buffer
+		out := service.writer().Out
+		if concurrency > 1 {
+			writer := *service.writer()
+			writer.Out = &buf
+			service.Writer = &writer
+		}
+
+		start := time.Now()
+		err := bootstrapService(ctx, service)
+		results[i] = BootstrapResult{Service: service.Name, Err: err, Duration: time.Since(start)}
+
+		mu.Lock()
+		defer mu.Unlock()
+		out.Write(buf.Bytes())
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range services {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// printSummary writes a table of the results to w.
+func printSummary(w io.Writer, results []BootstrapResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tSTATUS\tDURATION\tERROR")
+	for _, r := range results {
+		var msg string
+		if r.Err != nil {
+			msg = strings.ReplaceAll(r.Err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Service, r.Status(), r.Duration.Round(100*time.Millisecond), msg)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestServices returns services scaffolded into a temporary directory
+// that initialize with runner and write their diffs to out.
+func newTestServices(t *testing.T, runner *TerraformRunner, out *bytes.Buffer, names ...string) []*Service {
+	t.Helper()
+	path := t.TempDir()
+	services := make([]*Service, len(names))
+	for i, name := range names {
+		services[i] = NewService(name, path, "", "")
+		services[i].Runner = runner
+		services[i].Writer = &FileWriter{Out: out}
+	}
+	return services
+}
+
+func TestBootstrapAllBoundsConcurrency(t *testing.T) {
+	locks := t.TempDir()
+	// Every init records how many inits are running alongside it.
+	runner := fakeTerraform(t, `mkdir "`+locks+`/$$"
+ls "`+locks+`" | wc -l >> "`+locks+`.log"
+sleep 0.2
+rmdir "`+locks+`/$$"`)
+	var out bytes.Buffer
+	services := newTestServices(t, runner, &out, "a", "b", "c", "d", "e")
+
+	results := BootstrapAll(context.Background(), services, 2)
+	for i, result := range results {
+		if result.Service != services[i].Name || result.Err != nil {
+			t.Errorf("expected %s to succeed, got %+v", services[i].Name, result)
+		}
+	}
+
+	log, err := os.ReadFile(locks + ".log")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, line := range strings.Fields(string(log)) {
+		if n, _ := strconv.Atoi(line); n > 2 {
+			t.Errorf("expected at most 2 concurrent inits, got %d", n)
+		}
+	}
+}
+
+func TestBootstrapAllReportsFailures(t *testing.T) {
+	runner := fakeTerraform(t, `case "$1" in
+  */broken) echo "Error: provider not found" >&2; exit 1 ;;
+esac`)
+	var out bytes.Buffer
+	services := newTestServices(t, runner, &out, "web", "broken", "cache")
+
+	results := BootstrapAll(context.Background(), services, 3)
+	statuses := make([]string, len(results))
+	for i, result := range results {
+		statuses[i] = result.Status()
+	}
+	if strings.Join(statuses, ",") != "ok,failed,ok" {
+		t.Errorf("expected only broken to fail, got %v", statuses)
+	}
+
+	var summary bytes.Buffer
+	printSummary(&summary, results)
+	lines := strings.Split(strings.TrimSpace(summary.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "SERVICE") {
+		t.Fatalf("expected a header and a row per service, got:\n%s", summary.String())
+	}
+	if !strings.Contains(lines[2], "broken") || !strings.Contains(lines[2], "Error: provider not found") {
+		t.Errorf("expected the failure in the summary, got '%s'", lines[2])
+	}
+}
+
+func TestBootstrapAllSharesPluginCache(t *testing.T) {
+	cache := t.TempDir()
+	runner := fakeTerraform(t, `echo "$TF_PLUGIN_CACHE_DIR" > "${1#-chdir=}/cache.env"`)
+	runner.PluginCacheDir = cache
+	var out bytes.Buffer
+	services := newTestServices(t, runner, &out, "web", "cache")
+
+	BootstrapAll(context.Background(), services, 2)
+	for _, service := range services {
+		env, _ := os.ReadFile(filepath.Join(service.Dir(), "cache.env"))
+		if strings.TrimSpace(string(env)) != cache {
+			t.Errorf("expected %s to use the plugin cache, got '%s'", service.Name, env)
+		}
+	}
+}
+
+func TestBootstrapAllSerializesPluginCacheInits(t *testing.T) {
+	locks := t.TempDir()
+	runner := fakeTerraform(t, `mkdir "`+locks+`/$$"
+ls "`+locks+`" | wc -l >> "`+locks+`.log"
+sleep 0.1
+rmdir "`+locks+`/$$"`)
+	runner.PluginCacheDir = t.TempDir()
+	var out bytes.Buffer
+	services := newTestServices(t, runner, &out, "a", "b", "c", "d")
+
+	for _, result := range BootstrapAll(context.Background(), services, 4) {
+		if result.Err != nil {
+			t.Errorf("expected %s to succeed, got %+v", result.Service, result)
+		}
+	}
+
+	log, err := os.ReadFile(locks + ".log")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, line := range strings.Fields(string(log)) {
+		if n, _ := strconv.Atoi(line); n > 1 {
+			t.Errorf("expected inits sharing a plugin cache to run one at a time, got %d", n)
+		}
+	}
+}
+
+func TestBootstrapAllDoesNotInterleaveDiffs(t *testing.T) {
+	var out bytes.Buffer
+	services := newTestServices(t, nil, &out, "web", "cache", "queue")
+	for _, service := range services {
+		service.Writer.DryRun = true
+	}
+
+	BootstrapAll(context.Background(), services, 3)
+	for _, service := range services {
+		first := strings.Index(out.String(), service.Dir()+string(filepath.Separator))
+		last := strings.LastIndex(out.String(), service.Dir()+string(filepath.Separator))
+		if first < 0 {
+			t.Fatalf("expected the diff of %s in the output", service.Name)
+		}
+		for _, other := range services {
+			if other != service && strings.Contains(out.String()[first:last], other.Dir()+string(filepath.Separator)) {
+				t.Errorf("expected the diff of %s not to be interleaved with %s", service.Name, other.Name)
+			}
+		}
+	}
+}
+
+func TestBootstrapAllCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	services := newTestServices(t, fakeTerraform(t, "true"), &out, "web")
+
+	results := BootstrapAll(ctx, services, 1)
+	if results[0].Status() != "cancelled" {
+		t.Errorf("expected a cancelled service, got %+v", results[0])
+	}
+}
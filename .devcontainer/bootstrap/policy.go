@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// embeddedPolicy is the provider policy used when no -provider-policy is
+// given.
+//
+//go:embed provider-policy.yaml
+var embeddedPolicy []byte
+
+// defaultProviderPolicy parses the embedded policy once.
+var defaultProviderPolicy = sync.OnceValues(func() (*ProviderPolicy, error) {
+	return ParseProviderPolicy(embeddedPolicy)
+})
+
+// requiredProviders are the providers the templates pin, which every
+// policy must therefore define.
+var requiredProviders = []string{"helm", "kubernetes", "vault"}
+
+var (
+	providerSourceRe = regexp.MustCompile(`^([a-z0-9.-]+/)?[a-z0-9-]+/[a-z0-9-]+$`)
+	platformRe       = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+)
+
+// ProviderPolicy pins the versions of Terraform and of the providers the
+// generated configuration requires.
+type ProviderPolicy struct {
+	// Terraform is the required_version of every root module, e.g. "~> 1.11".
+	Terraform string `yaml:"terraform"`
+	// Platforms are the platforms lock files record checksums for, e.g.
+	// linux_amd64.
+	Platforms []string `yaml:"platforms"`
+	// Providers maps local provider names, as used in the templates, to
+	// their pins. Every provider in requiredProviders must be pinned.
+	Providers map[string]ProviderPin `yaml:"providers"`
+}
+
+// ProviderPin is the policy for a single provider.
+type ProviderPin struct {
+	// Source is the provider's registry address, e.g. hashicorp/helm.
+	Source string `yaml:"source"`
+	// Version is the exact version root modules require.
+	Version string `yaml:"version"`
+	// Constraint is the range of versions child modules accept. Version
+	// must satisfy it.
+	Constraint string `yaml:"constraint"`
+}
+
+// LoadProviderPolicy reads and validates the policy at path. An empty path
+// selects the embedded policy.
+func LoadProviderPolicy(path string) (*ProviderPolicy, error) {
+	if path == "" {
+		return defaultProviderPolicy()
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParseProviderPolicy(content)
+	if err != nil {
+		return nil, fmt.Errorf("provider policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParseProviderPolicy parses and validates a policy in YAML.
+func ParseProviderPolicy(content []byte) (*ProviderPolicy, error) {
+	var p ProviderPolicy
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parsing provider policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *ProviderPolicy) validate() error {
+	if p.Terraform == "" {
+		return fmt.Errorf("terraform: a version constraint is required")
+	}
+	if _, err := terraformConstraint(p.Terraform); err != nil {
+		return fmt.Errorf("terraform: invalid constraint %q: %w", p.Terraform, err)
+	}
+	for _, platform := range p.Platforms {
+		if !platformRe.MatchString(platform) {
+			return fmt.Errorf("platforms: invalid platform %q, expected <os>_<arch>, e.g. linux_amd64", platform)
+		}
+	}
+	if len(p.Providers) == 0 {
+		return fmt.Errorf("providers: at least one provider is required")
+	}
+	for _, name := range requiredProviders {
+		if _, ok := p.Providers[name]; !ok {
+			return fmt.Errorf("providers.%s: required by the generated configuration", name)
+		}
+	}
+	for _, name := range p.ProviderNames() {
+		if err := p.Providers[name].validate(); err != nil {
+			return fmt.Errorf("providers.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p ProviderPin) validate() error {
+	if !providerSourceRe.MatchString(p.Source) {
+		return fmt.Errorf("invalid source %q, expected [<hostname>/]<namespace>/<type>", p.Source)
+	}
+	version, err := semver.StrictNewVersion(p.Version)
+	if err != nil {
+		return fmt.Errorf("version %q is not an exact version: %w", p.Version, err)
+	}
+	if p.Constraint == "" {
+		return nil
+	}
+	constraint, err := terraformConstraint(p.Constraint)
+	if err != nil {
+		return fmt.Errorf("invalid constraint %q: %w", p.Constraint, err)
+	}
+	if !constraint.Check(version) {
+		return fmt.Errorf("version %s does not satisfy the constraint %q", p.Version, p.Constraint)
+	}
+	return nil
+}
+
+// ProviderNames returns the names of the pinned providers, sorted.
+func (p *ProviderPolicy) ProviderNames() []string {
+	names := make([]string, 0, len(p.Providers))
+	for name := range p.Providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// MinimumVersion returns the constraint of a child module for pin: its
+// constraint, or at least its version when it has none.
+func (p ProviderPin) MinimumVersion() string {
+	if p.Constraint != "" {
+		return p.Constraint
+	}
+	return ">= " + strings.TrimPrefix(p.Version, "v")
+}
+
+// terraformConstraint parses a Terraform version constraint. Terraform's
+// pessimistic operator only allows the rightmost version component to
+// grow, so "~> 1.11" is ">= 1.11, < 2" and "~> 1.11.0" is ">= 1.11.0,
+// < 1.12", which differs from the tilde operator of semver.
+func terraformConstraint(constraint string) (*semver.Constraints, error) {
+	parts := strings.Split(constraint, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		version, ok := strings.CutPrefix(part, "~>")
+		if !ok {
+			parts[i] = part
+			continue
+		}
+		version = strings.TrimSpace(version)
+		segments := strings.Split(version, ".")
+		if len(segments) < 2 {
+			return nil, fmt.Errorf("%q needs at least a major and a minor version", part)
+		}
+		// The component before the last one is bumped for the upper bound.
+		upper := make([]string, len(segments)-1)
+		copy(upper, segments)
+		n, err := strconv.Atoi(upper[len(upper)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version in %q: %w", part, err)
+		}
+		upper[len(upper)-1] = strconv.Itoa(n + 1)
+		parts[i] = fmt.Sprintf(">= %s, < %s", version, strings.Join(upper, "."))
+	}
+	return semver.NewConstraint(strings.Join(parts, ", "))
+}
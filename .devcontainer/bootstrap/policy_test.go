@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+func TestEmbeddedProviderPolicy(t *testing.T) {
+	policy, err := LoadProviderPolicy("")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	// The templates require these providers.
+	for _, name := range []string{"helm", "kubernetes", "vault"} {
+		if _, ok := policy.Providers[name]; !ok {
+			t.Errorf("expected the embedded policy to pin %s", name)
+		}
+	}
+	if len(policy.Platforms) == 0 {
+		t.Errorf("expected the embedded policy to list platforms")
+	}
+}
+
+func TestProviderPolicyErrors(t *testing.T) {
+	// others pins the providers besides helm that the templates require.
+	others := "  kubernetes:\n    source: hashicorp/kubernetes\n    version: 2.38.0\n  vault:\n    source: hashicorp/vault\n    version: 5.0.0\n"
+	valid := "terraform: \"~> 1.11\"\nproviders:\n  helm:\n    source: hashicorp/helm\n    version: 2.17.0\n" + others
+	if _, err := ParseProviderPolicy([]byte(valid)); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	tests := map[string]string{
+		"missing terraform":      "providers:\n  helm:\n    source: hashicorp/helm\n    version: 2.17.0\n" + others,
+		"no providers":           "terraform: \"~> 1.11\"\n",
+		"unknown field":          valid + "mirror: /tmp\n",
+		"invalid source":         "terraform: \"~> 1.11\"\nproviders:\n  helm:\n    source: helm\n    version: 2.17.0\n" + others,
+		"version range":          "terraform: \"~> 1.11\"\nproviders:\n  helm:\n    source: hashicorp/helm\n    version: \">= 2.17\"\n" + others,
+		"unsatisfied":            "terraform: \"~> 1.11\"\nproviders:\n  helm:\n    source: hashicorp/helm\n    version: 3.0.0\n    constraint: \"~> 2.17\"\n" + others,
+		"invalid platform":       valid + "platforms: [linux-amd64]\n",
+		"pessimistic major only": "terraform: \"~> 1\"\nproviders:\n  helm:\n    source: hashicorp/helm\n    version: 2.17.0\n" + others,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseProviderPolicy([]byte(content)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestProviderPolicyRequiresTemplateProviders(t *testing.T) {
+	content := "terraform: \"~> 1.11\"\nproviders:\n  kubernetes:\n    source: hashicorp/kubernetes\n    version: 2.38.0\n  vault:\n    source: hashicorp/vault\n    version: 5.0.0\n"
+	_, err := ParseProviderPolicy([]byte(content))
+	if err == nil || !strings.Contains(err.Error(), "providers.helm") {
+		t.Errorf("expected an error naming the missing helm provider, got '%v'", err)
+	}
+}
+
+func TestTerraformConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"~> 2.17", "2.17.0", true},
+		{"~> 2.17", "2.99.1", true},
+		{"~> 2.17", "3.0.0", false},
+		{"~> 2.17.0", "2.17.5", true},
+		{"~> 2.17.0", "2.18.0", false},
+		{">= 1.5, < 2.0", "1.11.3", true},
+		{"2.17.0", "2.17.1", false},
+	}
+	for _, tt := range tests {
+		c, err := terraformConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("expected no error for %q, got '%v'", tt.constraint, err)
+		}
+		if got := c.Check(semver.MustParse(tt.version)); got != tt.expected {
+			t.Errorf("expected %q to accept %s: %v, got %v", tt.constraint, tt.version, tt.expected, got)
+		}
+	}
+}
+
+func TestProviderPolicyPinsGeneratedVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	content := `terraform: ">= 1.9"
+providers:
+  helm:
+    source: example.com/acme/helm
+    version: 2.16.1
+  kubernetes:
+    source: hashicorp/kubernetes
+    version: 2.30.0
+    constraint: ">= 2.30, < 3.0"
+  vault:
+    source: hashicorp/vault
+    version: 5.0.0
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	policy, err := LoadProviderPolicy(path)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	service := NewService("web", "test-path", "", "")
+	service.Kind = KindModule
+	service.Features = Features{Namespace: true}
+	service.Policy = policy
+	contents := renderModuleFiles(t, service)
+
+	expected := map[string][]string{
+		"web/terraform.tf": {
+			`required_version = ">= 1.9"`,
+			`source  = "example.com/acme/helm"`,
+			`version = "2.16.1"`,
+			`version = "2.30.0"`,
+		},
+		"modules/web/versions.tf": {
+			`required_version = ">= 1.9"`,
+			`version = ">= 2.16.1"`,
+			`version = ">= 2.30, < 3.0"`,
+		},
+	}
+	for file, snippets := range expected {
+		for _, snippet := range snippets {
+			if !strings.Contains(contents[file], snippet) {
+				t.Errorf("expected %s to contain %q, got:\n%s", file, snippet, contents[file])
+			}
+		}
+	}
+}
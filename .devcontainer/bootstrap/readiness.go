@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"scripts/healthcheck"
+)
+
+const (
+	defaultReadinessTimeout  = 5 * time.Minute
+	defaultReadinessInterval = 10 * time.Second
+
+	// Helm annotates every resource of a release with its name and namespace.
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// ReadinessGate waits for the Deployments and StatefulSets of a Helm release
+// to be rolled out and then, optionally, for its health endpoint to answer.
+type ReadinessGate struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Release   string
+	// HealthURL, when set, is probed the way healthz does once every
+	// workload is rolled out.
+	HealthURL string
+	// Interval is the time between checks, Timeout bounds the whole wait.
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// NotReadyError is returned when a release does not become ready in time.
+type NotReadyError struct {
+	Release string
+	// Pending describes the workloads that were not rolled out.
+	Pending []string
+	// Last is the last failure of the check that was running.
+	Last error
+	Err  error
+}
+
+func (e *NotReadyError) Error() string {
+	msg := fmt.Sprintf("release %s did not become ready: %v", e.Release, e.Err)
+	switch {
+	case len(e.Pending) > 0:
+		msg += "; not rolled out: " + strings.Join(e.Pending, ", ")
+	case e.Last != nil:
+		msg += "; " + e.Last.Error()
+	}
+	return msg
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+// ReadinessGate creates a gate for the release of an applied service. The
+// cluster, namespace and release name are read from the service's variable
+// files, those of env when it is set.
+func (s *Service) ReadinessGate(env string) (*ReadinessGate, error) {
+	files := []string{filepath.Join(s.Dir(), "terraform.tfvars")}
+	if env != "" {
+		files = append(files, filepath.Join(s.Dir(), envVarFile(env)))
+	}
+	vars, err := readTfvars(files...)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewKubeClient(firstNonEmpty(vars["kubeconfig"], s.KubeConfig), firstNonEmpty(vars["config_context"], s.Context))
+	if err != nil {
+		return nil, err
+	}
+	return &ReadinessGate{
+		Client:    client,
+		Namespace: firstNonEmpty(vars["namespace"], s.Namespace),
+		Release:   firstNonEmpty(vars["release_name"], s.Name),
+		Interval:  defaultReadinessInterval,
+		Timeout:   defaultReadinessTimeout,
+	}, nil
+}
+
+// Wait blocks until the release is ready or the gate's timeout expires.
+func (g *ReadinessGate) Wait(ctx context.Context) error {
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	interval := g.Interval
+	if interval <= 0 {
+		interval = defaultReadinessInterval
+	}
+	logger := log.With().Str("release", g.Release).Str("namespace", g.Namespace).Logger()
+
+	var pending []string
+	var last error
+	rollout := func(ctx context.Context) error {
+		var err error
+		if pending, err = g.Pending(ctx); err != nil {
+			last = err
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d workloads not rolled out", len(pending))
+		}
+		return nil
+	}
+	err := healthcheck.Wait(ctx, interval, rollout, func(error) {
+		logger.Info().Strs("pending", pending).Msg("Waiting for the release to roll out")
+	})
+	if err != nil {
+		return &NotReadyError{Release: g.Release, Pending: pending, Last: last, Err: ctx.Err()}
+	}
+	logger.Info().Msg("Release rolled out")
+
+	if g.HealthURL == "" {
+		return nil
+	}
+	probe := &healthcheck.HTTPProbe{URL: g.HealthURL}
+	err = healthcheck.Wait(ctx, interval, probe.Check, func(err error) {
+		last = err
+		logger.Info().Err(err).Msg("Service not ready yet. Retrying...")
+	})
+	if err != nil {
+		return &NotReadyError{Release: g.Release, Last: fmt.Errorf("health probe: %w", last), Err: ctx.Err()}
+	}
+	logger.Info().Str("url", g.HealthURL).Msg("Service is up and running!")
+	return nil
+}
+
+// Pending describes the Deployments and StatefulSets of the release that
+// are not rolled out yet, e.g. "deployment/web: 1 of 2 updated replicas
+// available". A release without any of them is pending too, as its
+// workloads may not have been created yet.
+func (g *ReadinessGate) Pending(ctx context.Context) ([]string, error) {
+	var pending []string
+	found := 0
+	deployments, err := g.Client.AppsV1().Deployments(g.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments in %s: %w", g.Namespace, err)
+	}
+	for _, d := range deployments.Items {
+		if !g.owns(d.ObjectMeta) {
+			continue
+		}
+		found++
+		if status := deploymentPending(&d); status != "" {
+			pending = append(pending, "deployment/"+d.Name+": "+status)
+		}
+	}
+
+	statefulSets, err := g.Client.AppsV1().StatefulSets(g.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing statefulsets in %s: %w", g.Namespace, err)
+	}
+	for _, s := range statefulSets.Items {
+		if !g.owns(s.ObjectMeta) {
+			continue
+		}
+		found++
+		if status := statefulSetPending(&s); status != "" {
+			pending = append(pending, "statefulset/"+s.Name+": "+status)
+		}
+	}
+	if found == 0 {
+		pending = append(pending, fmt.Sprintf("no deployment or statefulset of release %s in %s", g.Release, g.Namespace))
+	}
+	return pending, nil
+}
+
+// owns reports whether the object belongs to the gate's release.
+func (g *ReadinessGate) owns(meta metav1.ObjectMeta) bool {
+	if meta.Annotations[helmReleaseNameAnnotation] != g.Release {
+		return false
+	}
+	namespace, ok := meta.Annotations[helmReleaseNamespaceAnnotation]
+	return !ok || namespace == g.Namespace
+}
+
+// deploymentPending returns why d is not rolled out, or "" when it is,
+// following kubectl rollout status.
+func deploymentPending(d *appsv1.Deployment) string {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	status := d.Status
+	switch {
+	case status.ObservedGeneration < d.Generation:
+		return "update not observed yet"
+	case status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		return fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas)
+	}
+	return ""
+}
+
+// statefulSetPending returns why s is not rolled out, or "" when it is,
+// following kubectl rollout status.
+func statefulSetPending(s *appsv1.StatefulSet) string {
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	status := s.Status
+	switch {
+	case status.ObservedGeneration < s.Generation:
+		return "update not observed yet"
+	case status.ReadyReplicas < replicas:
+		return fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, replicas)
+	}
+
+	// Pods are only replaced automatically with the rolling update strategy.
+	if s.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return ""
+	}
+	if rolling := s.Spec.UpdateStrategy.RollingUpdate; rolling != nil && rolling.Partition != nil && *rolling.Partition > 0 {
+		if updated := replicas - *rolling.Partition; status.UpdatedReplicas < updated {
+			return fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, updated)
+		}
+		return ""
+	}
+	if status.UpdateRevision != status.CurrentRevision {
+		return fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)
+	}
+	return ""
+}
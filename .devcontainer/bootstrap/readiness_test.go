@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func releaseMeta(name, release string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:       name,
+		Namespace:  "frontend",
+		Generation: 2,
+		Annotations: map[string]string{
+			helmReleaseNameAnnotation:      release,
+			helmReleaseNamespaceAnnotation: "frontend",
+		},
+	}
+}
+
+func deployment(name, release string, replicas, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: releaseMeta(name, release),
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(replicas)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           replicas,
+			UpdatedReplicas:    replicas,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func statefulSet(name, release string, replicas, ready int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: releaseMeta(name, release),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:       int32Ptr(replicas),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+		},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      ready,
+			UpdatedReplicas:    replicas,
+			CurrentRevision:    "web-1",
+			UpdateRevision:     "web-1",
+		},
+	}
+}
+
+func newTestGate(objects ...runtime.Object) *ReadinessGate {
+	return &ReadinessGate{
+		Client:    fake.NewSimpleClientset(objects...),
+		Namespace: "frontend",
+		Release:   "web",
+		Interval:  10 * time.Millisecond,
+		Timeout:   time.Second,
+	}
+}
+
+func TestReadinessPending(t *testing.T) {
+	gate := newTestGate(
+		deployment("web", "web", 2, 1),
+		deployment("web-ready", "web", 1, 1),
+		statefulSet("web-db", "web", 3, 2),
+		deployment("other", "other", 2, 0),
+	)
+
+	pending, err := gate.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := []string{
+		"deployment/web: 1 of 2 updated replicas available",
+		"statefulset/web-db: 2 of 3 replicas ready",
+	}
+	if strings.Join(pending, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %v, got %v", expected, pending)
+	}
+}
+
+func TestWorkloadPending(t *testing.T) {
+	d := deployment("web", "web", 2, 2)
+	d.Status.ObservedGeneration = 1
+	if got := deploymentPending(d); got != "update not observed yet" {
+		t.Errorf("unexpected deployment status '%s'", got)
+	}
+
+	s := statefulSet("web", "web", 2, 2)
+	s.Status.UpdateRevision, s.Status.UpdatedReplicas = "web-2", 1
+	if got := statefulSetPending(s); got != "1 of 2 replicas updated" {
+		t.Errorf("unexpected statefulset status '%s'", got)
+	}
+	s.Spec.UpdateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType
+	if got := statefulSetPending(s); got != "" {
+		t.Errorf("expected OnDelete statefulsets not to wait for updates, got '%s'", got)
+	}
+}
+
+func TestReadinessWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	gate := newTestGate(deployment("web", "web", 2, 2), statefulSet("web-db", "web", 1, 1))
+	gate.HealthURL = server.URL
+	if err := gate.Wait(context.Background()); err != nil {
+		t.Errorf("expected no error, got '%v'", err)
+	}
+}
+
+func TestReadinessWaitTimeout(t *testing.T) {
+	gate := newTestGate(deployment("web", "web", 2, 1))
+	gate.Timeout = 50 * time.Millisecond
+
+	err := gate.Wait(context.Background())
+	var notReady *NotReadyError
+	if !errors.As(err, &notReady) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a NotReadyError after the deadline, got '%v'", err)
+	}
+	if !strings.Contains(err.Error(), "deployment/web: 1 of 2 updated replicas available") {
+		t.Errorf("expected the pending workload in '%v'", err)
+	}
+}
+
+func TestReadinessWaitWithoutWorkloads(t *testing.T) {
+	gate := newTestGate(deployment("other", "other", 1, 1))
+	gate.Timeout = 50 * time.Millisecond
+
+	err := gate.Wait(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the gate to wait for the release's workloads, got '%v'", err)
+	}
+	if !strings.Contains(err.Error(), "no deployment or statefulset of release web in frontend") {
+		t.Errorf("expected the missing workloads in '%v'", err)
+	}
+}
+
+func TestReadinessWaitHealthProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	gate := newTestGate(deployment("web", "web", 1, 1))
+	gate.HealthURL = server.URL
+	gate.Timeout = 50 * time.Millisecond
+
+	err := gate.Wait(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "health probe: "+server.URL+" answered 503 Service Unavailable") {
+		t.Errorf("expected the probe failure, got '%v'", err)
+	}
+}
+
+func TestServiceReadinessGate(t *testing.T) {
+	service := NewService("web", t.TempDir(), "", "")
+	kubeConfig, _ := filepath.Abs(filepath.Join("testdata", "kube", "config"))
+	files := map[string]string{
+		"terraform.tfvars": "release_name = \"web\"\nkubeconfig = \"" + kubeConfig + "\"\nconfig_context = \"kind-dev\"\nnamespace = \"web\"\n",
+		envVarFile("prod"): "environment = \"prod\"\nnamespace = \"web-prod\"\n",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(service.Dir(), name), content); err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+	}
+
+	gate, err := service.ReadinessGate("prod")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if gate.Release != "web" || gate.Namespace != "web-prod" {
+		t.Errorf("expected web in web-prod, got %s in %s", gate.Release, gate.Namespace)
+	}
+
+	os.Remove(filepath.Join(service.Dir(), "terraform.tfvars"))
+	if _, err := service.ReadinessGate(""); err == nil {
+		t.Errorf("expected an error without terraform.tfvars")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// readmeFile documents the inputs and outputs of a generated module. Unlike
+// the other generated files it is rewritten on every run.
+const readmeFile = "README.md"
+
+// readmeMarker opens every generated README.
+const readmeMarker = "<!-- Generated by bootstrap from the module's variables and outputs, changes are overwritten. -->"
+
+// moduleDoc lists the variables and outputs a module declares, in the
+// order they are declared.
+type moduleDoc struct {
+	Inputs  []inputDoc
+	Outputs []outputDoc
+}
+
+type inputDoc struct {
+	Name        string
+	Description string
+	Type        string
+	// Default is the source of the default value, "" when the variable is
+	// required.
+	Default string
+}
+
+type outputDoc struct {
+	Name        string
+	Description string
+}
+
+// createReadmes documents the service's root module and, for services of
+// kind module, its child module. The variables and outputs are read from
+// the Terraform files in each directory, so files changed since they were
+// generated are documented as they are on disk.
+func (s *Service) createReadmes(files []fileInfo) error {
+	dirs := []string{s.Dir()}
+	if s.IsModule() {
+		dirs = append(dirs, s.ModuleDir())
+	}
+	writer := *s.writer()
+	writer.Policy = ConflictOverwrite
+	for _, dir := range dirs {
+		sources, err := moduleSources(dir, files)
+		if err != nil {
+			return err
+		}
+		doc, err := parseModuleDoc(sources)
+		if err != nil {
+			return fmt.Errorf("documenting %s: %w", dir, err)
+		}
+		path := filepath.Join(dir, readmeFile)
+		result, err := writer.Write(path, s.readme(dir, doc))
+		if err != nil {
+			return err
+		}
+		logWrite(path, result)
+	}
+	return nil
+}
+
+// moduleSources returns the Terraform files of dir by name: those on disk,
+// and generated files that are not written yet, as in a dry run.
+func moduleSources(dir string, files []fileInfo) (map[string][]byte, error) {
+	sources := map[string][]byte{}
+	for _, file := range files {
+		if filepath.Dir(file.path) == dir && filepath.Ext(file.path) == ".tf" {
+			sources[filepath.Base(file.path)] = []byte(file.content)
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		content, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.Base(match)] = content
+	}
+	return sources, nil
+}
+
+// parseModuleDoc collects the variables and outputs declared in sources,
+// Terraform files by name. Files are read in name order.
+func parseModuleDoc(sources map[string][]byte) (moduleDoc, error) {
+	var doc moduleDoc
+	parser := hclparse.NewParser()
+	for _, name := range slices.Sorted(maps.Keys(sources)) {
+		src := sources[name]
+		file, diags := parser.ParseHCL(src, name)
+		if diags.HasErrors() {
+			return doc, diags
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		for _, block := range body.Blocks {
+			if len(block.Labels) != 1 {
+				continue
+			}
+			switch block.Type {
+			case "variable":
+				doc.Inputs = append(doc.Inputs, inputDoc{
+					Name:        block.Labels[0],
+					Description: stringAttribute(block.Body, "description"),
+					Type:        attributeSource(src, block.Body, "type"),
+					Default:     attributeSource(src, block.Body, "default"),
+				})
+			case "output":
+				doc.Outputs = append(doc.Outputs, outputDoc{
+					Name:        block.Labels[0],
+					Description: stringAttribute(block.Body, "description"),
+				})
+			}
+		}
+	}
+	return doc, nil
+}
+
+// stringAttribute returns the value of a literal string attribute of body,
+// or "" when it is not set or not a literal.
+func stringAttribute(body *hclsyntax.Body, name string) string {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return ""
+	}
+	return value.AsString()
+}
+
+// attributeSource returns the expression of an attribute of body as
+// written in src, on a single line.
+func attributeSource(src []byte, body *hclsyntax.Body, name string) string {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	return strings.Join(strings.Fields(string(attr.Expr.Range().SliceBytes(src))), " ")
+}
+
+// readme renders the README of the module in dir.
+func (s *Service) readme(dir string, doc moduleDoc) string {
+	var sb strings.Builder
+	sb.WriteString(readmeMarker + "\n\n")
+	chart := fmt.Sprintf("`%s` chart", s.Chart.Name)
+	if dir == s.ModuleDir() {
+		fmt.Fprintf(&sb, "# %s Module\n\n", s.Name)
+		fmt.Fprintf(&sb, "Deploys the %s as a Helm release. Called by [%s](%s).\n", chart, s.Name, filepath.ToSlash(filepath.Join("..", "..", s.Name)))
+	} else {
+		fmt.Fprintf(&sb, "# %s\n\n", s.Name)
+		if s.IsModule() {
+			fmt.Fprintf(&sb, "Deploys the %s as the Helm release `%s` through the [%s module](%s).\n", chart, s.Name, s.Name, s.ModuleSource())
+		} else {
+			fmt.Fprintf(&sb, "Deploys the %s as the Helm release `%s`.\n", chart, s.Name)
+		}
+	}
+
+	if len(doc.Inputs) > 0 {
+		rows := make([][]string, len(doc.Inputs))
+		for i, input := range doc.Inputs {
+			def := "required"
+			if input.Default != "" {
+				def = "`" + input.Default + "`"
+			}
+			rows[i] = []string{"`" + input.Name + "`", input.Description, input.Type, def}
+		}
+		sb.WriteString("\n## Inputs\n\n")
+		sb.WriteString(markdownTable([]string{"Name", "Description", "Type", "Default"}, rows))
+	}
+	if len(doc.Outputs) > 0 {
+		rows := make([][]string, len(doc.Outputs))
+		for i, output := range doc.Outputs {
+			rows[i] = []string{"`" + output.Name + "`", output.Description}
+		}
+		sb.WriteString("\n## Outputs\n\n")
+		sb.WriteString(markdownTable([]string{"Name", "Description"}, rows))
+	}
+	return sb.String()
+}
+
+// markdownTable renders a table with its columns padded to the same width,
+// the way the module READMEs of this repository are laid out.
+func markdownTable(header []string, rows [][]string) string {
+	widths := make([]int, len(header))
+	for i, cell := range header {
+		widths[i] = len(cell)
+	}
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.ReplaceAll(row[i], "|", `\|`)
+			widths[i] = max(widths[i], len(row[i]))
+		}
+	}
+
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i, cell := range cells {
+			fmt.Fprintf(&sb, " %-*s |", widths[i], cell)
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(header)
+	separator := make([]string, len(header))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	writeRow(separator)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return sb.String()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadmeDocumentsInputsAndOutputs(t *testing.T) {
+	service := NewService("web", t.TempDir(), "", "")
+	service.Writer = &FileWriter{Out: &bytes.Buffer{}}
+	if err := service.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(service.Dir(), readmeFile))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	readme := string(content)
+	for _, expected := range []string{
+		readmeMarker,
+		"# web\n",
+		"## Inputs",
+		"| `release_name`        | application name ",
+		"| `chart_name`          | Name of the Helm chart to be deployed ",
+		"`\"web\"`",
+		"| required ",
+		"`[]`",
+		"## Outputs",
+		"| `status`        | Status of the Helm release ",
+	} {
+		if !strings.Contains(readme, expected) {
+			t.Errorf("expected %q in README:\n%s", expected, readme)
+		}
+	}
+}
+
+func TestReadmeFollowsCustomisedVariables(t *testing.T) {
+	service := NewService("web", t.TempDir(), "", "")
+	service.Writer = &FileWriter{Out: &bytes.Buffer{}}
+	if err := service.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	// The customised file is kept, the README is regenerated from it.
+	custom := "variable \"replicas\" {\n  type        = number\n  description = \"Replicas | pods\"\n}\n"
+	if err := os.WriteFile(filepath.Join(service.Dir(), "variables.tf"), []byte(custom), 0644); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(service.Dir(), readmeFile))
+	readme := string(content)
+	if !strings.Contains(readme, "| `replicas` | Replicas \\| pods | number | required |") {
+		t.Errorf("expected the README to document the customised variables, got:\n%s", readme)
+	}
+	if strings.Contains(readme, "`chart_name`") {
+		t.Errorf("expected removed variables to be dropped, got:\n%s", readme)
+	}
+}
+
+func TestReadmeOfChildModule(t *testing.T) {
+	service := NewService("web", t.TempDir(), "", "")
+	service.Kind = KindModule
+	service.Writer = &FileWriter{DryRun: true, Out: &bytes.Buffer{}}
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	sources, err := moduleSources(service.ModuleDir(), files)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	doc, err := parseModuleDoc(sources)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	readme := service.readme(service.ModuleDir(), doc)
+	for _, expected := range []string{"# web Module\n", "Called by [web](../../web)", "`values`", "`chart_version`"} {
+		if !strings.Contains(readme, expected) {
+			t.Errorf("expected %q in README:\n%s", expected, readme)
+		}
+	}
+	if strings.Contains(readme, "`kubeconfig`") {
+		t.Errorf("expected only the child module's inputs, got:\n%s", readme)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultTerraformBinary  = "terraform"
+	defaultTerraformTimeout = 10 * time.Minute
+
+	// terraformGracePeriod is how long terraform gets to release its state
+	// lock after being interrupted before it is killed.
+	terraformGracePeriod = 10 * time.Second
+)
+
+// TerraformRunner runs the terraform binary without a shell in between.
+type TerraformRunner struct {
+	// Binary is the terraform executable, looked up in PATH unless it
+	// contains a path separator.
+	Binary string
+	// Timeout bounds every command. Zero means no timeout.
+	Timeout time.Duration
+	// Env is appended to the environment of the current process.
+	Env []string
+	// PluginCacheDir, when set, is shared by every terraform init so that
+	// providers are downloaded once.
+	PluginCacheDir string
+	// Logger receives terraform's output line by line.
+	Logger zerolog.Logger
+}
+
+// NewTerraformRunner creates a runner for binary, defaulting to terraform in
+// PATH.
+func NewTerraformRunner(binary string, timeout time.Duration) *TerraformRunner {
+	if binary == "" {
+		binary = defaultTerraformBinary
+	}
+	return &TerraformRunner{Binary: binary, Timeout: timeout, Logger: log.Logger}
+}
+
+// TerraformResult holds the separately captured output of a command.
+type TerraformResult struct {
+	Stdout []byte
+	Stderr []byte
+}
+
+// TerraformError is returned when terraform could not be run or exited
+// unsuccessfully.
+type TerraformError struct {
+	Args []string
+	// ExitCode is the exit status of terraform, or -1 when it did not exit
+	// on its own, e.g. because it could not be started or was cancelled.
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *TerraformError) Error() string {
+	msg := fmt.Sprintf("terraform %s", strings.Join(e.Args, " "))
+	if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" exited with code %d", e.ExitCode)
+	} else {
+		msg += fmt.Sprintf(" failed: %v", e.Err)
+	}
+	if stderr := lastLine(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *TerraformError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs terraform with args in dir. Output is streamed to the logger and
+// returned; a non-zero exit is reported as a *TerraformError.
+func (r *TerraformRunner) Run(ctx context.Context, dir string, args ...string) (*TerraformResult, error) {
+	return r.run(ctx, dir, true, args)
+}
+
+// Output runs terraform like Run but only captures stdout, for commands
+// whose output is machine-readable such as show -json.
+func (r *TerraformRunner) Output(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	result, err := r.run(ctx, dir, false, args)
+	if err != nil {
+		return nil, err
+	}
+	return result.Stdout, nil
+}
+
+func (r *TerraformRunner) run(ctx context.Context, dir string, logStdout bool, args []string) (*TerraformResult, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	argv := append([]string{"-chdir=" + dir}, args...)
+	cmd := exec.CommandContext(ctx, r.Binary, argv...)
+	cmd.Env = append(os.Environ(), r.Env...)
+	cmd.Env = append(cmd.Env, "TF_IN_AUTOMATION=1")
+	if r.PluginCacheDir != "" {
+		// Reuse cached providers even though the lock file of a freshly
+		// scaffolded service does not list their checksums yet.
+		cmd.Env = append(cmd.Env,
+			"TF_PLUGIN_CACHE_DIR="+r.PluginCacheDir,
+			"TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE=true",
+		)
+	}
+	// Interrupt first so terraform can release its state lock.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = terraformGracePeriod
+
+	stdout := &lineLogger{logger: r.Logger, level: zerolog.InfoLevel, stream: "stdout"}
+	if !logStdout {
+		stdout.level = zerolog.Disabled
+	}
+	stderr := &lineLogger{logger: r.Logger, level: zerolog.WarnLevel, stream: "stderr"}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	r.Logger.Debug().Str("binary", r.Binary).Strs("args", argv).Msg("Running terraform")
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	result := &TerraformResult{Stdout: stdout.buf.Bytes(), Stderr: stderr.buf.Bytes()}
+	if err == nil {
+		return result, nil
+	}
+	tfErr := &TerraformError{Args: args, ExitCode: -1, Stderr: stderr.buf.String(), Err: err}
+	var exitErr *exec.ExitError
+	if ctx.Err() != nil {
+		tfErr.Err = ctx.Err()
+	} else if errors.As(err, &exitErr) {
+		tfErr.ExitCode = exitErr.ExitCode()
+	}
+	return result, tfErr
+}
+
+// pluginCacheLocks holds a lock per plugin cache directory.
+var pluginCacheLocks sync.Map
+
+// Init runs terraform init in dir with any extra args. Inits sharing a
+// plugin cache run one at a time, since terraform does not guard the cache
+// against concurrent writers.
+func (r *TerraformRunner) Init(ctx context.Context, dir string, args ...string) error {
+	if r.PluginCacheDir != "" {
+		unlock, err := lockPluginCache(ctx, r.PluginCacheDir)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	_, err := r.Run(ctx, dir, append([]string{"init", "-input=false", "-no-color"}, args...)...)
+	return err
+}
+
+// lockPluginCache waits until no other init uses the plugin cache in dir.
+func lockPluginCache(ctx context.Context, dir string) (func(), error) {
+	v, _ := pluginCacheLocks.LoadOrStore(dir, make(chan struct{}, 1))
+	lock := v.(chan struct{})
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// lineLogger captures a stream of output and logs it line by line.
+type lineLogger struct {
+	logger  zerolog.Logger
+	level   zerolog.Level
+	stream  string
+	buf     bytes.Buffer
+	pending []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf.Write(p)
+	l.pending = append(l.pending, p...)
+	for {
+		i := bytes.IndexByte(l.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		l.log(l.pending[:i])
+		l.pending = l.pending[i+1:]
+	}
+}
+
+// Flush logs a trailing line that was not terminated by a newline.
+func (l *lineLogger) Flush() {
+	l.log(l.pending)
+	l.pending = nil
+}
+
+func (l *lineLogger) log(line []byte) {
+	if l.level == zerolog.Disabled {
+		return
+	}
+	if text := strings.TrimRight(string(line), "\r"); strings.TrimSpace(text) != "" {
+		l.logger.WithLevel(l.level).Str("stream", l.stream).Msg(text)
+	}
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTerraform returns a runner for a shell script standing in for
+// terraform. The script receives the same argv terraform would.
+func fakeTerraform(t *testing.T, script string) *TerraformRunner {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "terraform")
+	err := os.WriteFile(binary, []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	return NewTerraformRunner(binary, time.Minute)
+}
+
+func TestTerraformRunnerArgv(t *testing.T) {
+	runner := fakeTerraform(t, `for arg in "$@"; do echo "[$arg]"; done; echo warning >&2`)
+	dir := filepath.Join(t.TempDir(), "dir with spaces; rm -rf")
+
+	result, err := runner.Run(context.Background(), dir, "plan", "-var=name=a b")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := "[-chdir=" + dir + "]\n[plan]\n[-var=name=a b]\n"
+	if string(result.Stdout) != expected {
+		t.Errorf("expected stdout %q, got %q", expected, result.Stdout)
+	}
+	if string(result.Stderr) != "warning\n" {
+		t.Errorf("expected stderr to be captured separately, got %q", result.Stderr)
+	}
+}
+
+func TestTerraformRunnerExitCode(t *testing.T) {
+	runner := fakeTerraform(t, `echo "Error: backend not configured" >&2; exit 3`)
+
+	_, err := runner.Run(context.Background(), t.TempDir(), "init")
+	var tfErr *TerraformError
+	if !errors.As(err, &tfErr) {
+		t.Fatalf("expected a TerraformError, got '%v'", err)
+	}
+	if tfErr.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", tfErr.ExitCode)
+	}
+	if !strings.Contains(err.Error(), "Error: backend not configured") {
+		t.Errorf("expected the error to include stderr, got '%v'", err)
+	}
+}
+
+func TestTerraformRunnerTimeout(t *testing.T) {
+	runner := fakeTerraform(t, `exec sleep 10`)
+	runner.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := runner.Run(context.Background(), t.TempDir(), "apply")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got '%v'", err)
+	}
+	var tfErr *TerraformError
+	if !errors.As(err, &tfErr) || tfErr.ExitCode != -1 {
+		t.Errorf("expected a TerraformError without exit code, got '%v'", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the command to be interrupted, took %s", time.Since(start))
+	}
+}
+
+func TestTerraformRunnerCancel(t *testing.T) {
+	runner := fakeTerraform(t, `exec sleep 10`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := runner.Run(ctx, t.TempDir(), "apply"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got '%v'", err)
+	}
+}
+
+func TestTerraformRunnerMissingBinary(t *testing.T) {
+	runner := NewTerraformRunner(filepath.Join(t.TempDir(), "missing"), time.Minute)
+
+	_, err := runner.Run(context.Background(), t.TempDir(), "init")
+	var tfErr *TerraformError
+	if !errors.As(err, &tfErr) || tfErr.ExitCode != -1 {
+		t.Errorf("expected a TerraformError without exit code, got '%v'", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// defaultVaultMount is the KV v2 engine vaultcli mounts.
+	defaultVaultMount   = "secret"
+	defaultVaultAddress = "http://127.0.0.1:8200"
+)
+
+var invalidLabelRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// VaultSecret is a KV v2 secret whose keys are passed to the Helm release
+// as sensitive values. The secret is read when Terraform plans, so its
+// values end up in the state but never in generated files.
+type VaultSecret struct {
+	// Mount is the path of the KV v2 engine, "secret" by default.
+	Mount string `yaml:"mount"`
+	// Path is the path of the secret within the mount, e.g. web/credentials.
+	Path string `yaml:"path"`
+	// Set maps chart values to keys of the secret, e.g.
+	// auth.password: password.
+	Set map[string]string `yaml:"set"`
+}
+
+// Label is the name of the secret's data source, derived from its mount and
+// path, e.g. secret_web_credentials. Names must not start with a digit, so
+// a mount such as 1password gives _1password_web.
+func (v VaultSecret) Label() string {
+	label := invalidLabelRe.ReplaceAllString(strings.ToLower(v.Mount+"_"+v.Path), "_")
+	label = strings.Trim(label, "_")
+	if label != "" && label[0] >= '0' && label[0] <= '9' {
+		label = "_" + label
+	}
+	return label
+}
+
+// withDefaults returns the secret with its mount defaulted.
+func (v VaultSecret) withDefaults() VaultSecret {
+	v.Mount = firstNonEmpty(strings.Trim(v.Mount, "/"), defaultVaultMount)
+	v.Path = strings.Trim(v.Path, "/")
+	return v
+}
+
+// validateSecrets rejects incomplete secrets, chart values set from more
+// than one secret and secrets whose data sources would share a name.
+func validateSecrets(secrets []VaultSecret) error {
+	labels := make(map[string]bool, len(secrets))
+	values := map[string]bool{}
+	for i, secret := range secrets {
+		secret = secret.withDefaults()
+		if secret.Path == "" {
+			return fmt.Errorf("secrets[%d]: path is required", i)
+		}
+		if len(secret.Set) == 0 {
+			return fmt.Errorf("secrets[%d]: set must map at least one chart value to a key of %s", i, secret.Path)
+		}
+		for name, key := range secret.Set {
+			if name == "" || key == "" {
+				return fmt.Errorf("secrets[%d]: set entries need both a chart value and a secret key", i)
+			}
+			if values[name] {
+				return fmt.Errorf("secrets[%d]: chart value %q is set by more than one secret", i, name)
+			}
+			values[name] = true
+		}
+		if labels[secret.Label()] {
+			return fmt.Errorf("secrets[%d]: duplicate secret %s/%s", i, secret.Mount, secret.Path)
+		}
+		labels[secret.Label()] = true
+	}
+	return nil
+}
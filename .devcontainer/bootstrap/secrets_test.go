@@ -0,0 +1,141 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestVaultSecretLabel(t *testing.T) {
+	secret := VaultSecret{Path: "/web/db-credentials/"}.withDefaults()
+	if secret.Mount != "secret" || secret.Path != "web/db-credentials" {
+		t.Errorf("unexpected defaults %+v", secret)
+	}
+	if secret.Label() != "secret_web_db_credentials" {
+		t.Errorf("unexpected label '%s'", secret.Label())
+	}
+
+	secret = VaultSecret{Mount: "1password", Path: "web"}.withDefaults()
+	if secret.Label() != "_1password_web" {
+		t.Errorf("expected a label that does not start with a digit, got '%s'", secret.Label())
+	}
+	if !hclsyntax.ValidIdentifier(secret.Label()) {
+		t.Errorf("expected '%s' to be a valid label", secret.Label())
+	}
+}
+
+func TestValidateSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []VaultSecret
+	}{
+		{"missing path", []VaultSecret{{Set: map[string]string{"auth.password": "password"}}}},
+		{"nothing set", []VaultSecret{{Path: "web"}}},
+		{"empty key", []VaultSecret{{Path: "web", Set: map[string]string{"auth.password": ""}}}},
+		{"value set twice", []VaultSecret{
+			{Path: "web", Set: map[string]string{"auth.password": "password"}},
+			{Path: "db", Set: map[string]string{"auth.password": "password"}},
+		}},
+		{"duplicate secret", []VaultSecret{
+			{Path: "web", Set: map[string]string{"a": "a"}},
+			{Mount: "secret/", Path: "/web", Set: map[string]string{"b": "b"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSecrets(tt.secrets); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestSecretsWiredIntoRelease(t *testing.T) {
+	service := NewService("web", "test-path", "", "")
+	service.VaultAddress = "https://vault.example.com"
+	service.Secrets = []VaultSecret{{
+		Path: "web/credentials",
+		Set:  map[string]string{"auth.password": "password", "auth.rootPassword": "root-password"},
+	}}
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	contents := map[string]string{}
+	for _, file := range files {
+		contents[filepath.Base(file.path)] = file.content
+	}
+
+	expected := map[string][]string{
+		"vault.tf": {
+			`data "vault_kv_secret_v2" "secret_web_credentials" {`,
+			`mount = "secret"`,
+			`name  = "web/credentials"`,
+		},
+		"main.tf": {
+			`name  = "auth.password"`,
+			`value = data.vault_kv_secret_v2.secret_web_credentials.data["password"]`,
+			`value = data.vault_kv_secret_v2.secret_web_credentials.data["root-password"]`,
+		},
+		"providers.tf": {`provider "vault" {`, `address = var.vault_address`},
+		"terraform.tf": {`source  = "hashicorp/vault"`},
+		"variables.tf": {`default     = "https://vault.example.com"`},
+	}
+	for file, lines := range expected {
+		for _, line := range lines {
+			if !strings.Contains(contents[file], line) {
+				t.Errorf("expected %q in %s:\n%s", line, file, contents[file])
+			}
+		}
+	}
+	if strings.Contains(contents["terraform.tfvars"], "password") {
+		t.Errorf("expected no credentials in terraform.tfvars, got:\n%s", contents["terraform.tfvars"])
+	}
+}
+
+func TestVaultFileLayout(t *testing.T) {
+	service := NewService("web", "test-path", "", "")
+	service.Secrets = []VaultSecret{{Path: "web/credentials"}, {Path: "web/tls"}}
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := `data "vault_kv_secret_v2" "secret_web_credentials" {
+  mount = "secret"
+  name  = "web/credentials"
+}
+
+data "vault_kv_secret_v2" "secret_web_tls" {
+  mount = "secret"
+  name  = "web/tls"
+}
+`
+	var content string
+	for _, file := range files {
+		if filepath.Base(file.path) == "vault.tf" {
+			content = file.content
+		}
+	}
+	if content != expected {
+		t.Errorf("expected vault.tf:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestNoVaultWithoutSecrets(t *testing.T) {
+	service := NewService("web", "test-path", "", "")
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, file := range files {
+		if filepath.Base(file.path) == "vault.tf" || strings.Contains(file.content, "vault") {
+			t.Errorf("expected no vault configuration, got %s:\n%s", file.path, file.content)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// valuesDir is the directory, relative to the service, holding the values
+// files passed to the Helm release.
+const valuesDir = "values"
+
+// Chart identifies the Helm chart a service deploys.
+type Chart struct {
+	Name       string `yaml:"name"`
+	Repository string `yaml:"repository"`
+	Version    string `yaml:"version"`
+}
+
+// Service represents a service with its configuration.
+type Service struct {
+	Name       string
+	Path       string
+	KubeConfig string
+	Context    string
+	Namespace  string
+	Chart      Chart
+
+	// ChartValues, when set, is written to values/<name>.yaml and passed to
+	// the Helm release before ValuesFiles.
+	ChartValues []byte
+
+	// Set overrides entries of ChartValues by dotted key, e.g. image.tag.
+	Set map[string]string
+
+	// ValuesFiles are values files copied into the service and passed to
+	// the Helm release in order.
+	ValuesFiles []string
+
+	// Environments the service is promoted to. Each gets its own variable
+	// file and backend configuration under env/, and the backend block is
+	// left for terraform init -backend-config to fill in.
+	Environments []Environment
+
+	// Kind is KindRoot or KindModule. An empty kind means KindRoot.
+	Kind string
+
+	// Features are the opt-in guardrails generated with the release.
+	Features Features
+
+	// Secrets are read from Vault at VaultAddress and passed to the
+	// release as sensitive values.
+	Secrets      []VaultSecret
+	VaultAddress string
+
+	// Backend generates the service's backend block. A nil value means
+	// the local backend.
+	Backend Backend
+
+	// Policy pins the versions of Terraform and the providers. A nil value
+	// means the embedded policy.
+	Policy *ProviderPolicy
+
+	// Templates is the template set the Terraform files are rendered from.
+	// A nil value means the embedded default set.
+	Templates *TemplateSet
+
+	// Writer writes the generated files. A nil value skips files that were
+	// changed since they were generated.
+	Writer *FileWriter
+
+	// Runner runs terraform in the service directory. A nil value runs
+	// terraform from PATH with the default timeout.
+	Runner *TerraformRunner
+}
+
+// NewService creates a new Service instance.
+func NewService(name, path, kubeConfig, context string) *Service {
+	return &Service{
+		Name:       name,
+		Path:       path,
+		KubeConfig: kubeConfig,
+		Context:    context,
+		Namespace:  name,
+		Chart: Chart{
+			Name:       name,
+			Repository: defaultChartRepo,
+		},
+	}
+}
+
+// Dir returns the directory the service is scaffolded into.
+func (s *Service) Dir() string {
+	return filepath.Join(s.Path, s.Name)
+}
+
+// CreateDirectory creates the directory for the service and, for services
+// of kind module, the directory of its child module.
+func (s *Service) CreateDirectory() error {
+	if err := s.writer().MkdirAll(s.Dir()); err != nil {
+		return err
+	}
+	if s.IsModule() {
+		return s.writer().MkdirAll(s.ModuleDir())
+	}
+	return nil
+}
+
+func (s *Service) writer() *FileWriter {
+	if s.Writer == nil {
+		return defaultWriter
+	}
+	return s.Writer
+}
+
+// CreateTerraformFiles creates necessary Terraform files and the READMEs
+// documenting them.
+func (s *Service) CreateTerraformFiles() error {
+	files, err := s.getTerraformFiles()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		result, err := s.writer().Write(file.path, file.content)
+		if err != nil {
+			log.Error().Err(err).Str("file", file.path).Msg("Error creating file")
+			return err
+		}
+		logWrite(file.path, result)
+	}
+	return s.createReadmes(files)
+}
+
+// CreateValuesFiles writes the chart values and copies the service's values
+// files into its values directory.
+func (s *Service) CreateValuesFiles() error {
+	if s.ChartValues == nil && len(s.ValuesFiles) == 0 {
+		return nil
+	}
+	written := map[string]string{}
+	if s.ChartValues != nil {
+		dst := filepath.Join(s.Dir(), s.chartValuesPath())
+		result, err := s.writer().Write(dst, string(s.ChartValues))
+		if err != nil {
+			return err
+		}
+		logWrite(dst, result)
+		written[dst] = "chart values"
+	}
+	for _, src := range s.ValuesFiles {
+		dst := filepath.Join(s.Dir(), valuesDir, filepath.Base(src))
+		if previous, ok := written[dst]; ok {
+			return fmt.Errorf("values file %s would overwrite %s at %s", src, previous, dst)
+		}
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		result, err := s.writer().Write(dst, string(content))
+		if err != nil {
+			return err
+		}
+		logWrite(dst, result)
+		written[dst] = src
+	}
+	return nil
+}
+
+// chartValuesPath returns the path of the generated chart values relative to
+// the service directory.
+func (s *Service) chartValuesPath() string {
+	return valuesDir + "/" + s.Name + ".yaml"
+}
+
+// valuesPaths returns the paths of the values files relative to the service
+// directory, in the order they are passed to the Helm release.
+func (s *Service) valuesPaths() []string {
+	var paths []string
+	if s.ChartValues != nil {
+		paths = append(paths, s.chartValuesPath())
+	}
+	for _, src := range s.ValuesFiles {
+		paths = append(paths, filepath.ToSlash(filepath.Join(valuesDir, filepath.Base(src))))
+	}
+	return paths
+}
+
+// getTerraformFiles renders every template of the service's template set
+// into the file it describes. HCL files are parsed back and formatted before
+// they are returned. Templates that render to nothing but whitespace, such
+// as those of disabled features, produce no file, and templates of the
+// child module are only rendered for services of kind module.
+func (s *Service) getTerraformFiles() ([]fileInfo, error) {
+	templates := s.Templates
+	if templates == nil {
+		var err error
+		if templates, err = LoadTemplates(""); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []fileInfo
+	for _, name := range templates.Names() {
+		if templates.IsModule(name) && !s.IsModule() {
+			continue
+		}
+		data := []templateData{s.templateData(nil)}
+		if templates.IsEnvironment(name) {
+			data = data[:0]
+			for _, env := range s.environments() {
+				data = append(data, s.templateData(&env))
+			}
+		}
+		for _, d := range data {
+			file, err := s.renderFile(templates, name, d)
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(file.content) != "" {
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// renderFile renders the named template into the file it describes.
+func (s *Service) renderFile(templates *TemplateSet, name string, data templateData) (fileInfo, error) {
+	var env string
+	if data.Environment != nil {
+		env = data.Environment.Name
+	}
+	dir := s.Dir()
+	if templates.IsModule(name) {
+		dir = s.ModuleDir()
+		data.ChildModule = true
+	}
+	content, err := templates.Render(name, data)
+	if err != nil {
+		return fileInfo{}, err
+	}
+	path := filepath.Join(dir, filepath.FromSlash(templates.File(name, env)))
+	if isHCL(path) {
+		formatted, err := formatHCL(path, []byte(content))
+		if err != nil {
+			return fileInfo{}, fmt.Errorf("template %s generated invalid HCL: %w", name, err)
+		}
+		content = string(formatted)
+	}
+	return fileInfo{path: path, content: content}, nil
+}
+
+type fileInfo struct {
+	path    string
+	content string
+}
+
+// templateData is the value templates are executed against.
+type templateData struct {
+	*Service
+	ValuesPaths  []string
+	Backend      backendData
+	Environments []Environment
+	Secrets      []VaultSecret
+	// Environment is the environment being rendered, nil outside of
+	// per-environment templates.
+	Environment *Environment
+	// ChildModule is set while rendering the templates of the child module.
+	ChildModule bool
+	Policy      *ProviderPolicy
+}
+
+// backendData describes the backend block to templates.
+type backendData struct {
+	Type       string
+	Attributes []hclAttribute
+}
+
+func (s *Service) templateData(env *Environment) templateData {
+	backend := s.Backend
+	if backend == nil {
+		backend, _ = newLocalBackend(nil)
+	}
+	var envName string
+	if env != nil {
+		envName = env.Name
+	}
+	return templateData{
+		Service:     s,
+		ValuesPaths: s.valuesPaths(),
+		Backend: backendData{
+			Type:       backend.Type(),
+			Attributes: backend.Attributes(s, envName),
+		},
+		Environments: s.environments(),
+		Secrets:      s.secrets(),
+		Environment:  env,
+		Policy:       s.policy(),
+	}
+}
+
+// policy returns the service's provider policy, the embedded one when it
+// has none.
+func (s *Service) policy() *ProviderPolicy {
+	if s.Policy != nil {
+		return s.Policy
+	}
+	// The embedded policy is covered by the tests.
+	policy, _ := defaultProviderPolicy()
+	return policy
+}
+
+// secrets returns the service's secrets with their defaults applied.
+func (s *Service) secrets() []VaultSecret {
+	secrets := make([]VaultSecret, len(s.Secrets))
+	for i, secret := range s.Secrets {
+		secrets[i] = secret.withDefaults()
+	}
+	return secrets
+}
+
+// InitializeTerraform initializes Terraform in the service directory. A
+// service with environments has no backend until one is selected, so only
+// its providers are installed.
+func (s *Service) InitializeTerraform(ctx context.Context) error {
+	if len(s.Environments) > 0 {
+		return s.terraform().Init(ctx, s.Dir(), "-backend=false")
+	}
+	return s.terraform().Init(ctx, s.Dir())
+}
+
+// terraform returns the service's runner logging with the service name.
+func (s *Service) terraform() *TerraformRunner {
+	runner := s.Runner
+	if runner == nil {
+		runner = NewTerraformRunner("", defaultTerraformTimeout)
+	}
+	scoped := *runner
+	scoped.Logger = runner.Logger.With().Str("service", s.Name).Logger()
+	return &scoped
+}
+
+func createFile(filePath, content string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -51,7 +52,11 @@ func TestCreateTerraformFiles(t *testing.T) {
 		t.Fatalf("expected no error, got '%v'", err)
 	}
 
-	for _, file := range service.getTerraformFiles() {
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	for _, file := range files {
 		if _, err := os.Stat(file.path); os.IsNotExist(err) {
 			t.Errorf("expected file '%s' to exist, but it does not", file.path)
 		}
@@ -60,6 +65,7 @@ func TestCreateTerraformFiles(t *testing.T) {
 
 func TestInitializeTerraform(t *testing.T) {
 	service := NewService("test-service", "test-path", "", "")
+	service.Runner = fakeTerraform(t, `echo "$@" > "${1#-chdir=}/init.args"`)
 	defer os.RemoveAll("test-path")
 
 	err := service.CreateDirectory()
@@ -67,8 +73,13 @@ func TestInitializeTerraform(t *testing.T) {
 		t.Fatalf("expected no error, got '%v'", err)
 	}
 
-	err = service.InitializeTerraform()
+	err = service.InitializeTerraform(context.Background())
 	if err != nil {
 		t.Errorf("expected no error, got '%v'", err)
 	}
+
+	args, _ := os.ReadFile(filepath.Join("test-path", "test-service", "init.args"))
+	if string(args) != "-chdir=test-path/test-service init -input=false -no-color\n" {
+		t.Errorf("unexpected terraform arguments '%s'", args)
+	}
 }
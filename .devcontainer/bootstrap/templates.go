@@ -0,0 +1,152 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateExt marks a file of a template set as a template. The rest of the
+// file name is the name of the file it renders, e.g. main.tf.tmpl -> main.tf.
+const templateExt = ".tmpl"
+
+// envTemplatePrefix marks a template that is rendered once per environment
+// into the environment directory, e.g. env.tfvars.tmpl -> env/prod.tfvars.
+const envTemplatePrefix = "env."
+
+// partialPrefix marks a template that only defines blocks for other
+// templates and renders no file of its own, e.g. _helm_release.tmpl.
+const partialPrefix = "_"
+
+// moduleTemplateDir holds the templates of the child module generated for
+// services of kind module, e.g. module/outputs.tf.tmpl.
+const moduleTemplateDir = "module"
+
+//go:embed templates/*.tmpl templates/module/*.tmpl
+var embeddedTemplates embed.FS
+
+// templateFuncs are the helpers available to every template.
+var templateFuncs = template.FuncMap{
+	"hcl":        hclString,
+	"attributes": hclAttributes,
+	"list":       hclList,
+	"ident":      hclIdentifier,
+}
+
+// TemplateSet is a collection of templates, one per generated file.
+type TemplateSet struct {
+	tmpl  *template.Template
+	files map[string]string
+}
+
+// LoadTemplates loads the template set found in dir. An empty dir selects
+// the embedded default set.
+func LoadTemplates(dir string) (*TemplateSet, error) {
+	if dir == "" {
+		sub, err := fs.Sub(embeddedTemplates, "templates")
+		if err != nil {
+			return nil, err
+		}
+		return ParseTemplates(sub)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template path %s is not a directory", dir)
+	}
+	return ParseTemplates(os.DirFS(dir))
+}
+
+// ParseTemplates parses every *.tmpl file at the root of fsys and in its
+// module directory.
+func ParseTemplates(fsys fs.FS) (*TemplateSet, error) {
+	matches, err := fs.Glob(fsys, "*"+templateExt)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := fs.Glob(fsys, moduleTemplateDir+"/*"+templateExt)
+	if err != nil {
+		return nil, err
+	}
+	matches = append(matches, modules...)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no %s templates found", templateExt)
+	}
+
+	set := &TemplateSet{
+		tmpl:  template.New("").Option("missingkey=error").Funcs(templateFuncs),
+		files: make(map[string]string, len(matches)),
+	}
+	for _, name := range matches {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := set.tmpl.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", name, err)
+		}
+		if strings.HasPrefix(path.Base(name), partialPrefix) {
+			continue
+		}
+		set.files[name] = strings.TrimSuffix(name, templateExt)
+	}
+	return set, nil
+}
+
+// Names returns the names of the templates in the set in a stable order.
+func (t *TemplateSet) Names() []string {
+	names := make([]string, 0, len(t.files))
+	for name := range t.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Files maps every template name to the name of the file it renders.
+func (t *TemplateSet) Files() map[string]string {
+	return t.files
+}
+
+// IsEnvironment reports whether the named template is rendered once per
+// environment.
+func (t *TemplateSet) IsEnvironment(name string) bool {
+	return strings.HasPrefix(t.files[name], envTemplatePrefix)
+}
+
+// IsModule reports whether the named template renders a file of the child
+// module.
+func (t *TemplateSet) IsModule(name string) bool {
+	return path.Dir(t.files[name]) == moduleTemplateDir
+}
+
+// File returns the file the named template renders for env, relative to
+// the service directory or, for templates of the child module, to the
+// module directory. env is ignored by templates that are not rendered per
+// environment.
+func (t *TemplateSet) File(name, env string) string {
+	file := t.files[name]
+	if t.IsModule(name) {
+		return path.Base(file)
+	}
+	if !t.IsEnvironment(name) {
+		return file
+	}
+	return envDir + "/" + env + strings.TrimPrefix(file, "env")
+}
+
+// Render executes the named template against data.
+func (t *TemplateSet) Render(name string, data any) (string, error) {
+	var sb strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&sb, name, data); err != nil {
+		return "", fmt.Errorf("rendering template %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
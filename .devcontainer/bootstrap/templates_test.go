@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadEmbeddedTemplates(t *testing.T) {
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	expected := []string{"backend.tf", "env.backend.hcl", "env.tfvars", "main.tf", "module/main.tf", "module/namespace.tf", "module/outputs.tf", "module/variables.tf", "module/vault.tf", "module/versions.tf", "namespace.tf", "outputs.tf", "providers.tf", "terraform.tf", "terraform.tfvars", "variables.tf", "vault.tf"}
+	var got []string
+	for _, name := range templates.Names() {
+		got = append(got, templates.Files()[name])
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected files %v, got %v", expected, got)
+	}
+}
+
+func TestRenderEmbeddedTemplates(t *testing.T) {
+	service := NewService("test-service", "test-path", "test-kubeconfig", "test-context")
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	contents := map[string]string{}
+	for _, file := range files {
+		contents[filepath.Base(file.path)] = file.content
+	}
+	if !strings.Contains(contents["main.tf"], `resource "helm_release" "test-service"`) {
+		t.Errorf("expected main.tf to declare the helm release, got:\n%s", contents["main.tf"])
+	}
+	if !strings.Contains(contents["variables.tf"], `default     = "test-kubeconfig"`) {
+		t.Errorf("expected variables.tf to default the kubeconfig, got:\n%s", contents["variables.tf"])
+	}
+	if !strings.Contains(contents["terraform.tfvars"], `config_context = "test-context"`) {
+		t.Errorf("expected terraform.tfvars to set the context, got:\n%s", contents["terraform.tfvars"])
+	}
+}
+
+func TestRenderWithoutContext(t *testing.T) {
+	service := NewService("test-service", "test-path", "test-kubeconfig", "")
+	service.Environments = []Environment{{Name: "dev"}}
+
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	contents := map[string]string{}
+	for _, file := range files {
+		contents[filepath.ToSlash(strings.TrimPrefix(file.path, service.Path+string(filepath.Separator)))] = file.content
+	}
+	for _, name := range []string{"test-service/terraform.tfvars", "test-service/env/dev.tfvars"} {
+		if strings.Contains(contents[name], "config_context") {
+			t.Errorf("expected %s to leave out the empty context, got:\n%s", name, contents[name])
+		}
+	}
+	if !strings.Contains(contents["test-service/variables.tf"], "default     = null") {
+		t.Errorf("expected the context to default to null, got:\n%s", contents["test-service/variables.tf"])
+	}
+}
+
+func TestLoadTemplatesFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "extra.tf.tmpl"), []byte(`# {{ .Name }} in {{ .Context }}`), 0644)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	service := NewService("test-service", "test-path", "", "test-context")
+	service.Templates = templates
+	files, err := service.getTerraformFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+	if files[0].path != filepath.Join("test-path", "test-service", "extra.tf") {
+		t.Errorf("expected extra.tf, got '%s'", files[0].path)
+	}
+	if files[0].content != "# test-service in test-context" {
+		t.Errorf("unexpected content '%s'", files[0].content)
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	if _, err := LoadTemplates(t.TempDir()); err == nil {
+		t.Errorf("expected an error for an empty template directory")
+	}
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "main.tf.tmpl"), []byte(`{{ .Unknown }}`), 0644)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if _, err := templates.Render("main.tf.tmpl", NewService("s", "p", "", "").templateData(nil)); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
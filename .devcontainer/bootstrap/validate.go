@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// maxReleaseNameLength is the longest release name Helm accepts, leaving
+// room for the suffixes charts append to it in resource names.
+const maxReleaseNameLength = 53
+
+var (
+	// releaseNameRe is the pattern Helm checks release names against.
+	releaseNameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	invalidNameRe = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ValidationError reports a field of a service that Helm or Kubernetes
+// would reject.
+type ValidationError struct {
+	// Field is the offending field, e.g. "name" or "environments[prod].namespace".
+	Field  string
+	Value  string
+	Reason string
+	// Suggestion is a valid value derived from Value, if there is one.
+	Suggestion string
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (try %q)", e.Suggestion)
+	}
+	return msg
+}
+
+// Validate checks the service name as a Helm release name that bootstrap
+// does not reserve, and every namespace as a DNS-1123 label. All problems
+// are reported together as *ValidationError values joined with
+// errors.Join, along with any problem with the service's secrets.
+func (s *Service) Validate() error {
+	var errs []error
+	if err := validateReleaseName("name", s.Name); err != nil {
+		errs = append(errs, err)
+	} else if err := validateServiceDir("name", s.Name); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateNamespace("namespace", s.Namespace); err != nil {
+		errs = append(errs, err)
+	}
+	for _, env := range s.Environments {
+		if env.Namespace == "" {
+			continue
+		}
+		if err := validateNamespace(fmt.Sprintf("environments[%s].namespace", env.Name), env.Namespace); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := validateSecrets(s.Secrets); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// validateReleaseName checks value against Helm's release name rules.
+func validateReleaseName(field, value string) error {
+	var reason string
+	switch {
+	case value == "":
+		reason = "must not be empty"
+	case len(value) > maxReleaseNameLength:
+		reason = fmt.Sprintf("must be no more than %d characters", maxReleaseNameLength)
+	case !releaseNameRe.MatchString(value):
+		reason = "must consist of lowercase alphanumeric characters, '-' or '.', and start and end with an alphanumeric character"
+	default:
+		return nil
+	}
+	return &ValidationError{Field: field, Value: value, Reason: reason, Suggestion: normalizeName(value, maxReleaseNameLength)}
+}
+
+// validateServiceDir rejects names whose directory bootstrap reserves,
+// such as the one holding the child modules of every service.
+func validateServiceDir(field, value string) error {
+	if value != modulesDir {
+		return nil
+	}
+	return &ValidationError{
+		Field:      field,
+		Value:      value,
+		Reason:     "is reserved for the directory of child modules",
+		Suggestion: value + "-service",
+	}
+}
+
+// validateNamespace checks value as a DNS-1123 label, which is what
+// Kubernetes requires of namespace names.
+func validateNamespace(field, value string) error {
+	reasons := validation.IsDNS1123Label(value)
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &ValidationError{
+		Field:      field,
+		Value:      value,
+		Reason:     strings.Join(reasons, "; "),
+		Suggestion: normalizeName(value, validation.DNS1123LabelMaxLength),
+	}
+}
+
+// normalizeName turns value into a DNS-1123 label of at most maxLength
+// characters, e.g. "My_App" becomes "my-app". It returns "" when nothing
+// usable is left.
+func normalizeName(value string, maxLength int) string {
+	name := invalidNameRe.ReplaceAllString(strings.ToLower(value), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "-")
+	}
+	return name
+}
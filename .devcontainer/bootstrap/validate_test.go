@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateService(t *testing.T) {
+	tests := []struct {
+		name      string
+		service   string
+		namespace string
+		field     string
+		suggested string
+	}{
+		{"valid", "web", "frontend", "", ""},
+		{"dotted release name", "web.v2", "frontend", "", ""},
+		{"uppercase name", "My_App", "frontend", "name", "my-app"},
+		{"long name", strings.Repeat("a", 54), "frontend", "name", strings.Repeat("a", 53)},
+		{"leading hyphen", "-web", "frontend", "name", "web"},
+		{"dotted namespace", "web", "front.end", "namespace", "front-end"},
+		{"long namespace", "web", strings.Repeat("n", 64), "namespace", strings.Repeat("n", 63)},
+		{"no suggestion", "___", "frontend", "name", ""},
+		{"reserved name", "modules", "frontend", "name", "modules-service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(tt.service, "test-path", "", "")
+			service.Namespace = tt.namespace
+
+			err := service.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("expected no error, got '%v'", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected a ValidationError, got '%v'", err)
+			}
+			if invalid.Field != tt.field || invalid.Suggestion != tt.suggested {
+				t.Errorf("expected field %s with suggestion %q, got %+v", tt.field, tt.suggested, invalid)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	service := NewService("Web", "test-path", "", "")
+	service.Namespace = "Front"
+	service.Environments = []Environment{{Name: "prod", Namespace: "Web_Prod"}}
+
+	err := service.Validate()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got '%v'", err)
+	}
+	var fields []string
+	for _, err := range joined.Unwrap() {
+		fields = append(fields, err.(*ValidationError).Field)
+	}
+	if strings.Join(fields, ",") != "name,namespace,environments[prod].namespace" {
+		t.Errorf("expected every invalid field, got %v", fields)
+	}
+	if !strings.Contains(err.Error(), `invalid name "Web"`) || !strings.Contains(err.Error(), `(try "web-prod")`) {
+		t.Errorf("unexpected message '%v'", err)
+	}
+}
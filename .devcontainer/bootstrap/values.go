@@ -0,0 +1,258 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	helmChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+)
+
+// FetchValues downloads the archive of the resolved chart and returns the
+// values.yaml it ships with.
+func (r *ChartResolver) FetchValues(chart Chart) ([]byte, error) {
+	archive, err := r.FetchArchive(chart)
+	if err != nil {
+		return nil, err
+	}
+	values, err := chartValues(archive)
+	if err != nil {
+		return nil, fmt.Errorf("chart %s %s: %w", chart.Name, chart.Version, err)
+	}
+	return values, nil
+}
+
+// FetchArchive downloads the packaged chart at its resolved version.
+func (r *ChartResolver) FetchArchive(chart Chart) ([]byte, error) {
+	if chart.IsOCI() {
+		return r.fetchOCIArchive(chart)
+	}
+
+	index, err := r.FetchIndex(chart.Repository)
+	if err != nil {
+		return nil, err
+	}
+	version, err := index.Find(chart.Name, chart.Version)
+	if err != nil {
+		return nil, fmt.Errorf("repository %s: %w", chart.Repository, err)
+	}
+	if len(version.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s %s has no download URL", chart.Name, version.Version)
+	}
+
+	// Archive URLs may be relative to the repository.
+	base, err := url.Parse(strings.TrimSuffix(chart.Repository, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	archiveURL, err := base.Parse(version.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Client.Get(archiveURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer drain(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", archiveURL, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchOCIArchive pulls the chart layer of an OCI chart.
+func (r *ChartResolver) fetchOCIArchive(chart Chart) ([]byte, error) {
+	registry, repository := chart.ociReference()
+	tag := strings.ReplaceAll(chart.Version, "+", "_")
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", r.registryScheme(), registry, repository, tag)
+
+	resp, err := r.registryGet(manifestURL, repository, ociManifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&manifest)
+	drain(resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest of %s:%s: %w", repository, tag, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != helmChartLayerMediaType {
+			continue
+		}
+		blobURL := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", r.registryScheme(), registry, repository, layer.Digest)
+		resp, err := r.registryGet(blobURL, repository, "")
+		if err != nil {
+			return nil, err
+		}
+		defer drain(resp)
+		return io.ReadAll(resp.Body)
+	}
+	return nil, fmt.Errorf("%s:%s is not a Helm chart", repository, tag)
+}
+
+// chartValues extracts values.yaml from the root of a packaged chart.
+func chartValues(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive has no values.yaml")
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Charts are packaged as <chart>/..., subcharts live deeper.
+		dir, file := path.Split(path.Clean(header.Name))
+		if file == "values.yaml" && dir != "" && !strings.Contains(strings.TrimSuffix(dir, "/"), "/") {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// applyOverrides sets each dotted key of overrides in the values document,
+// creating intermediate maps as needed. Values are parsed as YAML scalars so
+// replicaCount=2 sets a number. Comments in the document are preserved.
+// A literal dot in a key is escaped as "\.".
+func applyOverrides(values []byte, overrides map[string]string) ([]byte, error) {
+	if len(overrides) == 0 {
+		return values, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(values, &doc); err != nil {
+		return nil, fmt.Errorf("parsing values: %w", err)
+	}
+	// A file holding only comments, or a bare --- marker, has no map to set
+	// the keys in. Its text is kept and the keys are written after it.
+	var preamble []byte
+	if doc.Kind == 0 || isEmptyDocument(&doc) {
+		preamble = values
+		if len(preamble) > 0 && !bytes.HasSuffix(preamble, []byte("\n")) {
+			preamble = append(preamble, '\n')
+		}
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		*root = yaml.Node{Kind: yaml.MappingNode, HeadComment: root.HeadComment}
+	}
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var value yaml.Node
+		if err := yaml.Unmarshal([]byte(overrides[key]), &value); err != nil {
+			return nil, fmt.Errorf("parsing value of %s: %w", key, err)
+		}
+		// key= sets an empty string, key=null sets null.
+		scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}
+		if len(value.Content) > 0 {
+			scalar = value.Content[0]
+		}
+		if err := setValue(root, splitKey(key), scalar); err != nil {
+			return nil, fmt.Errorf("setting %s: %w", key, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(preamble)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isEmptyDocument reports whether doc holds nothing but a --- marker and
+// comments.
+func isEmptyDocument(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return true
+	}
+	root := doc.Content[0]
+	return root.Kind == yaml.ScalarNode && root.Tag == "!!null" && root.Value == ""
+}
+
+// setValue walks node along path and sets the last element to value.
+func setValue(node *yaml.Node, path []string, value *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a map", path[0])
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			value.LineComment = node.Content[i+1].LineComment
+			node.Content[i+1] = value
+			return nil
+		}
+		child := node.Content[i+1]
+		if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			*child = yaml.Node{Kind: yaml.MappingNode}
+		}
+		return setValue(child, path[1:], value)
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}
+	if len(path) == 1 {
+		node.Content = append(node.Content, key, value)
+		return nil
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, key, child)
+	return setValue(child, path[1:], value)
+}
+
+// splitKey splits a dotted key, honouring "\." escapes.
+func splitKey(key string) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key) && key[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case key[i] == '.':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(key[i])
+		}
+	}
+	return append(parts, current.String())
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const redisValues = `# Number of replicas
+replicaCount: 1 # keep odd
+
+image:
+  repository: bitnami/redis
+  tag: 7.2.0
+
+auth:
+`
+
+// chartArchive packages files the way helm package lays them out.
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+		if err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("expected no error, got '%v'", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	return buf.Bytes()
+}
+
+func redisArchive(t *testing.T) []byte {
+	return chartArchive(t, map[string]string{
+		"redis/Chart.yaml":                "apiVersion: v2\nname: redis\nversion: 18.0.0\n",
+		"redis/charts/common/values.yaml": "common: true\n",
+		"redis/values.yaml":               redisValues,
+	})
+}
+
+func TestChartValues(t *testing.T) {
+	values, err := chartValues(redisArchive(t))
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if string(values) != redisValues {
+		t.Errorf("expected the chart's values.yaml, got:\n%s", values)
+	}
+
+	if _, err := chartValues(chartArchive(t, map[string]string{"redis/Chart.yaml": ""})); err == nil {
+		t.Errorf("expected an error for a chart without values")
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	values, err := applyOverrides([]byte(redisValues), map[string]string{
+		"replicaCount":  "3",
+		"image.tag":     "7.2.4",
+		"auth.password": "secret",
+		"podLabels.app\\.kubernetes\\.io/part-of": "cache",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	for _, expected := range []string{
+		"# Number of replicas\nreplicaCount: 3 # keep odd\n",
+		"  tag: 7.2.4\n",
+		"auth:\n  password: secret\n",
+		"podLabels:\n  app.kubernetes.io/part-of: cache\n",
+	} {
+		if !strings.Contains(string(values), expected) {
+			t.Errorf("expected %q in:\n%s", expected, values)
+		}
+	}
+
+	if _, err := applyOverrides([]byte(redisValues), map[string]string{"image.tag.major": "7"}); err == nil {
+		t.Errorf("expected an error when setting a key below a scalar")
+	}
+}
+
+func TestApplyOverridesWithoutValues(t *testing.T) {
+	values, err := applyOverrides(nil, map[string]string{"service.type": "NodePort"})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if string(values) != "service:\n  type: NodePort\n" {
+		t.Errorf("unexpected values:\n%s", values)
+	}
+}
+
+func TestApplyOverridesToEmptyValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		expected string
+	}{
+		{
+			name:     "comments only",
+			values:   "# Settings go here\n# replicaCount: 1\n",
+			expected: "# Settings go here\n# replicaCount: 1\nservice:\n  type: NodePort\n",
+		},
+		{
+			name:     "document marker",
+			values:   "---\n",
+			expected: "---\nservice:\n  type: NodePort\n",
+		},
+		{
+			name:     "document marker and comments",
+			values:   "---\n# Settings go here",
+			expected: "---\n# Settings go here\nservice:\n  type: NodePort\n",
+		},
+		{
+			name:     "document marker before a map",
+			values:   "---\nreplicaCount: 1\n",
+			expected: "replicaCount: 1\nservice:\n  type: NodePort\n",
+		},
+		{
+			name:     "explicit null",
+			values:   "null\n",
+			expected: "service:\n  type: NodePort\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := applyOverrides([]byte(tt.values), map[string]string{"service.type": "NodePort"})
+			if err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			if string(values) != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, values)
+			}
+		})
+	}
+}
+
+func TestApplyOverridesEmptyAndNull(t *testing.T) {
+	values, err := applyOverrides([]byte("auth:\n  password: secret\n"), map[string]string{
+		"auth.password": "",
+		"auth.existing": "null",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := "auth:\n  password: \"\"\n  existing: null\n"
+	if string(values) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, values)
+	}
+}
+
+func TestFetchValues(t *testing.T) {
+	archive := redisArchive(t)
+	mux := http.NewServeMux()
+	mux.Handle("/index.yaml", http.FileServer(http.Dir("testdata")))
+	mux.HandleFunc("/redis-18.0.0.tgz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resolver := &ChartResolver{Client: server.Client()}
+	values, err := resolver.FetchValues(Chart{Name: "redis", Repository: server.URL, Version: "18.0.0"})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if string(values) != redisValues {
+		t.Errorf("expected the chart's values.yaml, got:\n%s", values)
+	}
+}
+
+func TestFetchOCIValues(t *testing.T) {
+	archive := redisArchive(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/charts/redis/manifests/18.0.0_build.1":
+			if r.Header.Get("Accept") != ociManifestMediaType {
+				http.Error(w, "bad accept", http.StatusNotAcceptable)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"layers": []map[string]string{
+					{"mediaType": "application/vnd.cncf.helm.config.v1+json", "digest": "sha256:config"},
+					{"mediaType": helmChartLayerMediaType, "digest": "sha256:chart"},
+				},
+			})
+		case "/v2/charts/redis/blobs/sha256:chart":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	resolver := &ChartResolver{Client: server.Client()}
+	registry := strings.TrimPrefix(server.URL, "https://")
+	values, err := resolver.FetchValues(Chart{Name: "redis", Repository: "oci://" + registry + "/charts", Version: "18.0.0+build.1"})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if string(values) != redisValues {
+		t.Errorf("expected the chart's values.yaml, got:\n%s", values)
+	}
+}
+
+func TestChartValuesFile(t *testing.T) {
+	service := NewService("redis", t.TempDir(), "", "")
+	service.ChartValues = []byte(redisValues)
+	service.ValuesFiles = []string{"testdata/values/web.yaml"}
+
+	if err := service.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateValuesFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := "values/redis.yaml,values/web.yaml"
+	if got := strings.Join(service.valuesPaths(), ","); got != expected {
+		t.Errorf("expected values paths %s, got %s", expected, got)
+	}
+
+	service.Name = "web"
+	if err := service.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateValuesFiles(); err == nil {
+		t.Errorf("expected an error when a values file overwrites the chart values")
+	}
+}
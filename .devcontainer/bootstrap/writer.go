@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/rs/zerolog/log"
+)
+
+// sidecarExt is appended to the path of a file whose regenerated content
+// is written next to it instead of over it.
+const sidecarExt = ".new"
+
+// ConflictPolicy decides what happens when a generated file already exists
+// with different content.
+type ConflictPolicy int
+
+const (
+	// ConflictSkip leaves the existing file untouched.
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite
+	// ConflictSidecar writes the generated content to <file>.new.
+	ConflictSidecar
+)
+
+// WriteResult reports what FileWriter.Write did with a file.
+type WriteResult string
+
+const (
+	WriteCreated     WriteResult = "created"
+	WriteUnchanged   WriteResult = "unchanged"
+	WriteSkipped     WriteResult = "skipped"
+	WriteOverwritten WriteResult = "overwritten"
+	WriteSidecar     WriteResult = "sidecar"
+)
+
+// FileWriter writes generated files without silently discarding changes
+// made to them since they were last generated. Differences are shown as a
+// unified diff on Out.
+type FileWriter struct {
+	Policy ConflictPolicy
+	// DryRun prints what would be written without touching the disk.
+	DryRun bool
+	Out    io.Writer
+}
+
+// defaultWriter skips conflicting files and reports on stdout.
+var defaultWriter = &FileWriter{Out: os.Stdout}
+
+// Write writes content to path according to the writer's policy.
+func (w *FileWriter) Write(path, content string) (WriteResult, error) {
+	current, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if w.DryRun {
+			fmt.Fprint(w.Out, unifiedDiff(path, "", content))
+			return WriteCreated, nil
+		}
+		return WriteCreated, writeFile(path, content)
+	}
+	if err != nil {
+		return "", err
+	}
+	if string(current) == content {
+		return WriteUnchanged, nil
+	}
+
+	fmt.Fprint(w.Out, unifiedDiff(path, string(current), content))
+	if w.DryRun {
+		return w.conflictResult(), nil
+	}
+	switch w.Policy {
+	case ConflictOverwrite:
+		return WriteOverwritten, writeFile(path, content)
+	case ConflictSidecar:
+		return WriteSidecar, writeFile(path+sidecarExt, content)
+	default:
+		return WriteSkipped, nil
+	}
+}
+
+// conflictResult is the result of writing a file that differs from disk.
+func (w *FileWriter) conflictResult() WriteResult {
+	switch w.Policy {
+	case ConflictOverwrite:
+		return WriteOverwritten
+	case ConflictSidecar:
+		return WriteSidecar
+	default:
+		return WriteSkipped
+	}
+}
+
+// MkdirAll creates path unless the writer is in dry-run mode.
+func (w *FileWriter) MkdirAll(path string) error {
+	if w.DryRun {
+		return nil
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+// logWrite logs the outcome of writing a generated file.
+func logWrite(path string, result WriteResult) {
+	switch result {
+	case WriteUnchanged:
+		log.Debug().Str("file", path).Msg("File unchanged")
+	case WriteSkipped:
+		log.Warn().Str("file", path).Msg("File differs from the generated content, skipping (use -force or -sidecar)")
+	case WriteSidecar:
+		log.Info().Str("file", path+sidecarExt).Msg("Writing generated content next to the existing file")
+	case WriteOverwritten:
+		log.Info().Str("file", path).Msg("Overwriting file")
+	default:
+		log.Info().Str("file", path).Msg("Creating file")
+	}
+}
+
+// unifiedDiff returns the unified diff from current to generated content.
+func unifiedDiff(path, current, generated string) string {
+	from, a := path, difflib.SplitLines(current)
+	if current == "" {
+		from, a = "/dev/null", nil
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        a,
+		B:        difflib.SplitLines(generated),
+		FromFile: from,
+		ToFile:   path,
+		Context:  3,
+	})
+	return diff
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return createFile(path, content)
+}
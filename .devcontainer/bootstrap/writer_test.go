@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		writer   FileWriter
+		existing string
+		result   WriteResult
+		content  string
+		sidecar  bool
+	}{
+		{"create", FileWriter{}, "", WriteCreated, "generated\n", false},
+		{"unchanged", FileWriter{}, "generated\n", WriteUnchanged, "generated\n", false},
+		{"skip", FileWriter{}, "customised\n", WriteSkipped, "customised\n", false},
+		{"force", FileWriter{Policy: ConflictOverwrite}, "customised\n", WriteOverwritten, "generated\n", false},
+		{"sidecar", FileWriter{Policy: ConflictSidecar}, "customised\n", WriteSidecar, "customised\n", true},
+		{"dry run", FileWriter{Policy: ConflictOverwrite, DryRun: true}, "customised\n", WriteOverwritten, "customised\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.tf")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatalf("expected no error, got '%v'", err)
+				}
+			}
+
+			var out bytes.Buffer
+			tt.writer.Out = &out
+			result, err := tt.writer.Write(path, "generated\n")
+			if err != nil {
+				t.Fatalf("expected no error, got '%v'", err)
+			}
+			if result != tt.result {
+				t.Errorf("expected result '%s', got '%s'", tt.result, result)
+			}
+
+			content, _ := os.ReadFile(path)
+			if string(content) != tt.content {
+				t.Errorf("expected content %q, got %q", tt.content, content)
+			}
+			_, err = os.Stat(path + sidecarExt)
+			if tt.sidecar != (err == nil) {
+				t.Errorf("expected sidecar to exist: %v, got '%v'", tt.sidecar, err)
+			}
+
+			conflict := tt.existing != "" && tt.existing != "generated\n"
+			if conflict && !strings.Contains(out.String(), "-customised\n+generated\n") {
+				t.Errorf("expected a diff, got:\n%s", out.String())
+			}
+			if tt.result == WriteUnchanged && out.Len() != 0 {
+				t.Errorf("expected no output for an unchanged file, got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestDryRunDoesNotTouchDisk(t *testing.T) {
+	var out bytes.Buffer
+	service := NewService("test-service", t.TempDir(), "", "")
+	service.Writer = &FileWriter{DryRun: true, Out: &out}
+
+	if err := service.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	if _, err := os.Stat(service.Dir()); !os.IsNotExist(err) {
+		t.Errorf("expected no service directory, got '%v'", err)
+	}
+	for _, expected := range []string{
+		"--- /dev/null\n+++ " + filepath.Join(service.Dir(), "main.tf") + "\n",
+		`+resource "helm_release" "test-service" {`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestRegenerationKeepsCustomisedFiles(t *testing.T) {
+	service := NewService("test-service", t.TempDir(), "", "")
+	service.Writer = &FileWriter{Out: &bytes.Buffer{}}
+
+	if err := service.CreateDirectory(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	mainTf := filepath.Join(service.Dir(), "main.tf")
+	if err := os.WriteFile(mainTf, []byte("# customised\n"), 0644); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if err := service.CreateTerraformFiles(); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+
+	content, _ := os.ReadFile(mainTf)
+	if string(content) != "# customised\n" {
+		t.Errorf("expected the customised main.tf to be kept, got:\n%s", content)
+	}
+}
@@ -0,0 +1,29 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// DNSProbe expects Host to resolve to at least one address.
+type DNSProbe struct {
+	Host     string
+	Resolver *net.Resolver
+}
+
+// Check resolves the probe's host once.
+func (p *DNSProbe) Check(ctx context.Context) error {
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupHost(ctx, p.Host)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%s resolved to no addresses", p.Host)
+	}
+	return nil
+}
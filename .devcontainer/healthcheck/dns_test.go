@@ -0,0 +1,69 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServer answers A queries for the names in records on a local UDP
+// port and returns a resolver using it.
+func dnsServer(t *testing.T, records map[string][4]byte) *net.Resolver {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			question := query.Questions[0]
+			answer := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeNameError},
+				Questions: query.Questions,
+			}
+			if ip, ok := records[question.Name.String()]; ok {
+				answer.RCode = dnsmessage.RCodeSuccess
+				if question.Type == dnsmessage.TypeA {
+					answer.Answers = []dnsmessage.Resource{{
+						Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.AResource{A: ip},
+					}}
+				}
+			}
+			packed, err := answer.Pack()
+			if err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+}
+
+func TestDNSProbe(t *testing.T) {
+	resolver := dnsServer(t, map[string][4]byte{"vault.alpine-net.": {10, 0, 0, 2}})
+
+	if err := (&DNSProbe{Host: "vault.alpine-net", Resolver: resolver}).Check(context.Background()); err != nil {
+		t.Errorf("expected vault to resolve, got '%v'", err)
+	}
+	if err := (&DNSProbe{Host: "postgres.alpine-net", Resolver: resolver}).Check(context.Background()); err == nil {
+		t.Errorf("expected an unknown host to fail")
+	}
+}
@@ -0,0 +1,60 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// maxExecOutput is the most of a failed command's output kept in its
+	// error.
+	maxExecOutput = 512
+	// execWaitDelay is how long a command is waited for once its context
+	// is done, in case it left children holding its output open.
+	execWaitDelay = time.Second
+)
+
+// ExecProbe expects Command, run without a shell, to exit with status 0.
+type ExecProbe struct {
+	Command []string
+	Dir     string
+	// Env is added to the environment of the process running the probe.
+	Env []string
+}
+
+// Check runs the probe's command once.
+func (p *ExecProbe) Check(ctx context.Context) error {
+	if len(p.Command) == 0 {
+		return Permanent(errors.New("no command to run"))
+	}
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Dir = p.Dir
+	if len(p.Env) > 0 {
+		cmd.Env = append(os.Environ(), p.Env...)
+	}
+	cmd.WaitDelay = execWaitDelay
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return Permanent(err)
+	}
+	if out := tail(strings.TrimSpace(string(output)), maxExecOutput); out != "" {
+		return fmt.Errorf("%s: %w: %s", p.Command[0], err, out)
+	}
+	return fmt.Errorf("%s: %w", p.Command[0], err)
+}
+
+// tail returns at most the last n bytes of s.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
@@ -0,0 +1,49 @@
+package healthcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecProbe(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	probe := &ExecProbe{Command: []string{"sh", "-c", `test -f "$READY_FILE" || { echo "waiting for $READY_FILE"; exit 1; }`}, Env: []string{"READY_FILE=" + ready}}
+
+	err := probe.Check(context.Background())
+	if err == nil || IsPermanent(err) || !strings.Contains(err.Error(), "exit status 1: waiting for "+ready) {
+		t.Errorf("expected a retryable failure with the output, got '%v'", err)
+	}
+	if err := os.WriteFile(ready, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("expected no error, got '%v'", err)
+	}
+}
+
+func TestExecProbeNotFound(t *testing.T) {
+	for _, probe := range []*ExecProbe{
+		{},
+		{Command: []string{"healthz-no-such-command"}},
+	} {
+		if err := probe.Check(context.Background()); !IsPermanent(err) {
+			t.Errorf("%v: expected a permanent failure, got '%v'", probe.Command, err)
+		}
+	}
+}
+
+func TestExecProbeTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := (&ExecProbe{Command: []string{"sleep", "10"}}).Check(ctx); err == nil {
+		t.Errorf("expected the command to be killed")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to stop with its context, took %s", elapsed)
+	}
+}
@@ -0,0 +1,193 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcHealthMethod is the path of the standard health check RPC.
+const grpcHealthMethod = "/grpc.health.v1.Health/Check"
+
+// Status codes of gRPC used by the probe.
+const (
+	grpcOK            = 0
+	grpcUnimplemented = 12
+)
+
+// grpcServing is the SERVING value of HealthCheckResponse.ServingStatus.
+const grpcServing = 1
+
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// defaultGRPCClient speaks HTTP/2 with and without TLS, as gRPC servers
+// only accept HTTP/2.
+var defaultGRPCClient = sync.OnceValue(func() *http.Client {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+})
+
+// GRPCProbe expects the standard gRPC health service,
+// grpc.health.v1.Health/Check, at Address to report Service as SERVING.
+// The RPC is small enough to be sent over HTTP/2 directly, without a gRPC
+// client.
+type GRPCProbe struct {
+	Address string
+	// Service is the service asked about, the whole server when empty.
+	Service string
+	// TLS connects with TLS instead of in plain text.
+	TLS bool
+	// Client must speak HTTP/2, in plain text unless TLS is set.
+	Client *http.Client
+}
+
+// Check calls the health service once.
+func (p *GRPCProbe) Check(ctx context.Context) error {
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		return Permanent(err)
+	}
+	client := p.Client
+	if client == nil {
+		client = defaultGRPCClient()
+	}
+	scheme := "http"
+	if p.TLS {
+		scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+"://"+p.Address+grpcHealthMethod, bytes.NewReader(p.request()))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+		resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", p.Address, resp.Status)
+	}
+	// The trailers are only set once the body is read to its end.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize))
+	if err != nil {
+		return fmt.Errorf("%s: reading response: %w", p.Address, err)
+	}
+	if err := grpcStatus(resp); err != nil {
+		return fmt.Errorf("%s: %w", p.Address, err)
+	}
+	status, err := servingStatus(body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.Address, err)
+	}
+	if status != grpcServing {
+		name, ok := grpcServingStatus[status]
+		if !ok {
+			name = strconv.FormatUint(status, 10)
+		}
+		return fmt.Errorf("%s reports %s", p.describe(), name)
+	}
+	return nil
+}
+
+// describe names the checked service in errors.
+func (p *GRPCProbe) describe() string {
+	if p.Service == "" {
+		return p.Address
+	}
+	return fmt.Sprintf("%s service %q", p.Address, p.Service)
+}
+
+// request returns the framed HealthCheckRequest for p.Service.
+func (p *GRPCProbe) request() []byte {
+	var message []byte
+	if p.Service != "" {
+		message = protowire.AppendTag(message, 1, protowire.BytesType)
+		message = protowire.AppendString(message, p.Service)
+	}
+	// A frame is an uncompressed flag and the big endian message length.
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// grpcStatus returns the failure the grpc-status of resp reports. Servers
+// send it as a trailer, or as a header when the response has no message.
+func grpcStatus(resp *http.Response) error {
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		return fmt.Errorf("response has no grpc-status, not a gRPC server?")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return fmt.Errorf("invalid grpc-status %q", status)
+	}
+	switch code {
+	case grpcOK:
+		return nil
+	case grpcUnimplemented:
+		// Retrying cannot register the health service.
+		return Permanent(fmt.Errorf("health service not implemented: %s", message))
+	default:
+		return fmt.Errorf("health check failed with gRPC status %d: %s", code, message)
+	}
+}
+
+// servingStatus decodes the status of the framed HealthCheckResponse in
+// body.
+func servingStatus(body []byte) (uint64, error) {
+	if len(body) < 5 {
+		return 0, fmt.Errorf("truncated gRPC response")
+	}
+	if body[0] != 0 {
+		return 0, fmt.Errorf("compressed gRPC responses are not supported")
+	}
+	length := binary.BigEndian.Uint32(body[1:5])
+	message := body[5:]
+	if uint32(len(message)) < length {
+		return 0, fmt.Errorf("truncated gRPC response")
+	}
+	message = message[:length]
+	// Fields left out hold their zero value, UNKNOWN for the status.
+	var status uint64
+	for len(message) > 0 {
+		number, kind, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return 0, fmt.Errorf("invalid health response: %w", protowire.ParseError(n))
+		}
+		message = message[n:]
+		if number == 1 && kind == protowire.VarintType {
+			status, n = protowire.ConsumeVarint(message)
+		} else {
+			n = protowire.ConsumeFieldValue(number, kind, message)
+		}
+		if n < 0 {
+			return 0, fmt.Errorf("invalid health response: %w", protowire.ParseError(n))
+		}
+		message = message[n:]
+	}
+	return status, nil
+}
@@ -0,0 +1,114 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcHealthServer implements grpc.health.v1.Health/Check over plain text
+// HTTP/2 with the statuses of services. Unknown services are NOT_FOUND.
+func grpcHealthServer(t *testing.T, statuses map[string]uint64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != grpcHealthMethod || r.Header.Get("Content-Type") != "application/grpc" {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "12")
+			w.Header().Set("Grpc-Message", "unknown method "+r.URL.Path)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var service string
+		if message := body[5:]; len(message) > 0 {
+			_, _, n := protowire.ConsumeTag(message)
+			service, _ = protowire.ConsumeString(message[n:])
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		status, ok := statuses[service]
+		if !ok {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+		message := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), status)
+		frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(message)))
+		w.Write(append(frame, message...))
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGRPCProbe(t *testing.T) {
+	server := grpcHealthServer(t, map[string]uint64{"": grpcServing, "vault": 2})
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	for _, test := range []struct {
+		service string
+		fails   string
+	}{
+		{service: ""},
+		{service: "vault", fails: `service "vault" reports NOT_SERVING`},
+		{service: "postgres", fails: "gRPC status 5: unknown service"},
+	} {
+		err := (&GRPCProbe{Address: address, Service: test.service}).Check(context.Background())
+		if test.fails == "" {
+			if err != nil {
+				t.Errorf("%q: expected no error, got '%v'", test.service, err)
+			}
+			continue
+		}
+		if err == nil || IsPermanent(err) || !strings.Contains(err.Error(), test.fails) {
+			t.Errorf("%q: expected a retryable error containing '%s', got '%v'", test.service, test.fails, err)
+		}
+	}
+}
+
+func TestGRPCProbeNotGRPC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// An HTTP/1 server does not answer over plain text HTTP/2.
+	err := (&GRPCProbe{Address: strings.TrimPrefix(server.URL, "http://")}).Check(context.Background())
+	if err == nil {
+		t.Errorf("expected an HTTP/1 server to fail")
+	}
+	if err := (&GRPCProbe{Address: "localhost"}).Check(context.Background()); !IsPermanent(err) {
+		t.Errorf("expected an address without port to be permanent, got '%v'", err)
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Grpc-Status": {"12"}, "Grpc-Message": {"unknown service grpc.health.v1.Health"}}}
+	if err := grpcStatus(resp); !IsPermanent(err) {
+		t.Errorf("expected UNIMPLEMENTED to be permanent, got '%v'", err)
+	}
+	if err := grpcStatus(&http.Response{Header: http.Header{}}); err == nil {
+		t.Errorf("expected a response without status to fail")
+	}
+}
+
+func TestServingStatus(t *testing.T) {
+	for name, body := range map[string][]byte{
+		"empty":      nil,
+		"compressed": {1, 0, 0, 0, 0},
+		"truncated":  {0, 0, 0, 0, 4, 8},
+	} {
+		if _, err := servingStatus(body); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	// An empty message leaves the status at its zero value, UNKNOWN.
+	if status, err := servingStatus([]byte{0, 0, 0, 0, 0}); err != nil || status != 0 {
+		t.Errorf("expected UNKNOWN, got %d, '%v'", status, err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package healthcheck probes a service until it reports healthy. It is
+// shared by healthz and bootstrap's readiness gate.
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Check probes a service once and returns why it is not healthy.
+type Check func(ctx context.Context) error
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure that retrying cannot fix, such as a
+// malformed request. Wait returns it without retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err is, or wraps, a failure marked with
+// Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Retry spaces the attempts of a check. The first retry waits Interval;
+// with a Multiplier above 1 every further retry waits that much longer, up
+// to MaxInterval.
+type Retry struct {
+	Interval time.Duration
+	// Multiplier grows the interval after every retry. 0 and 1 keep it
+	// fixed.
+	Multiplier  float64
+	MaxInterval time.Duration
+	// Jitter randomizes every interval by up to this fraction of it in
+	// either direction, e.g. 0.2 for ±20%, so that clients started
+	// together do not retry in lockstep.
+	Jitter float64
+	// AttemptTimeout bounds every single check, so that a request that
+	// hangs is retried instead of using up the whole deadline.
+	AttemptTimeout time.Duration
+}
+
+// Delay returns the interval before retry n, counting from 0, without
+// jitter.
+func (r Retry) Delay(n int) time.Duration {
+	delay := float64(r.Interval)
+	if r.Multiplier > 1 {
+		delay *= math.Pow(r.Multiplier, float64(n))
+	}
+	if r.MaxInterval > 0 && delay > float64(r.MaxInterval) {
+		delay = float64(r.MaxInterval)
+	}
+	return time.Duration(delay)
+}
+
+// jittered randomizes d by up to r.Jitter of it.
+func (r Retry) jittered(d time.Duration) time.Duration {
+	if r.Jitter <= 0 {
+		return d
+	}
+	return max(0, d+time.Duration(float64(d)*r.Jitter*(2*rand.Float64()-1)))
+}
+
+// Wait runs check every interval until it succeeds or ctx is done. onRetry,
+// when set, is called with every failure that is retried. When ctx ends
+// first, the returned error wraps both ctx's error and the last failure.
+// A check cut short by ctx only counts when no check failed before it.
+// Permanent failures are returned at once.
+func Wait(ctx context.Context, interval time.Duration, check Check, onRetry func(error)) error {
+	return Retry{Interval: interval}.Wait(ctx, check, onRetry)
+}
+
+// Wait runs check until it succeeds or ctx is done, like the package's
+// Wait, spacing the attempts as r describes.
+func (r Retry) Wait(ctx context.Context, check Check, onRetry func(error)) error {
+	var last error
+	for n := 0; ; n++ {
+		err := r.attempt(ctx, check)
+		if err == nil || IsPermanent(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			if last == nil {
+				last = err
+			}
+			return errors.Join(ctx.Err(), last)
+		}
+		last = err
+		if onRetry != nil {
+			onRetry(err)
+		}
+		timer := time.NewTimer(r.jittered(r.Delay(n)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), last)
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt runs check once, bounded by r.AttemptTimeout.
+func (r Retry) attempt(ctx context.Context, check Check) error {
+	if r.AttemptTimeout <= 0 {
+		return check(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.AttemptTimeout)
+	defer cancel()
+	return check(ctx)
+}
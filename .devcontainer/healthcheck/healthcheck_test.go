@@ -0,0 +1,90 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	failure := errors.New("connection refused")
+
+	err := Wait(ctx, 10*time.Millisecond, func(context.Context) error { return failure }, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, failure) {
+		t.Errorf("expected the deadline and the last failure, got '%v'", err)
+	}
+}
+
+func TestWaitKeepsFailureBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	failure := errors.New("503 Service Unavailable")
+
+	var calls int
+	err := Wait(ctx, 10*time.Millisecond, func(ctx context.Context) error {
+		if calls++; calls == 1 {
+			return failure
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the failure before the deadline, got '%v'", err)
+	}
+}
+
+func TestWaitPermanentFailure(t *testing.T) {
+	var calls int
+	err := Wait(context.Background(), time.Millisecond, func(context.Context) error {
+		calls++
+		return Permanent(errors.New("invalid request"))
+	}, nil)
+	if !IsPermanent(err) || calls != 1 {
+		t.Errorf("expected a single permanent failure, got '%v' after %d calls", err, calls)
+	}
+	if IsPermanent(errors.New("connection refused")) || Permanent(nil) != nil {
+		t.Errorf("expected only marked failures to be permanent")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	retry := Retry{Interval: time.Second, Multiplier: 2, MaxInterval: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for n, want := range expected {
+		if got := retry.Delay(n); got != want {
+			t.Errorf("expected retry %d to wait %s, got %s", n, want, got)
+		}
+	}
+	if got := (Retry{Interval: time.Second}).Delay(3); got != time.Second {
+		t.Errorf("expected a fixed interval without a multiplier, got %s", got)
+	}
+
+	retry.Jitter = 0.5
+	for range 100 {
+		if d := retry.jittered(4 * time.Second); d < 2*time.Second || d > 6*time.Second {
+			t.Fatalf("expected 4s ± 50%%, got %s", d)
+		}
+	}
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var calls int
+	retry := Retry{Interval: time.Millisecond, AttemptTimeout: 10 * time.Millisecond}
+	err := retry.Wait(ctx, func(ctx context.Context) error {
+		// The first attempt hangs until its own timeout.
+		if calls++; calls == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}, nil)
+	if err != nil || calls != 2 {
+		t.Errorf("expected the hanging attempt to be retried, got '%v' after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,188 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxBodySize is the most of a response body the probe reads for its
+	// body and JSON assertions.
+	MaxBodySize = 1 << 20
+	// maxDrain is the most of a response body read and discarded so that
+	// the connection can be reused. Longer bodies close the connection.
+	maxDrain = 64 << 10
+)
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min, Max int
+}
+
+// ParseStatusRange parses a status code such as "200", a class such as
+// "2xx" or a range such as "200-299".
+func ParseStatusRange(s string) (StatusRange, error) {
+	s = strings.TrimSpace(s)
+	var r StatusRange
+	var err error
+	switch {
+	case len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx"):
+		var class int
+		class, err = strconv.Atoi(s[:1])
+		r = StatusRange{Min: class * 100, Max: class*100 + 99}
+	case strings.Contains(s, "-"):
+		low, high, _ := strings.Cut(s, "-")
+		if r.Min, err = strconv.Atoi(strings.TrimSpace(low)); err == nil {
+			r.Max, err = strconv.Atoi(strings.TrimSpace(high))
+		}
+	default:
+		r.Min, err = strconv.Atoi(s)
+		r.Max = r.Min
+	}
+	if err != nil {
+		return StatusRange{}, fmt.Errorf("invalid status %q, expected e.g. 200, 2xx or 200-299", s)
+	}
+	if err := r.validate(); err != nil {
+		return StatusRange{}, err
+	}
+	return r, nil
+}
+
+func (r StatusRange) validate() error {
+	if r.Min < 100 || r.Max > 599 || r.Min > r.Max {
+		return fmt.Errorf("invalid status range %s, codes must be between 100 and 599", r)
+	}
+	return nil
+}
+
+// Contains reports whether code is in r.
+func (r StatusRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+func (r StatusRange) String() string {
+	if r.Min == r.Max {
+		return strconv.Itoa(r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// HTTPProbe expects a GET of URL to answer with a status in one of Status,
+// 200 OK when Status is empty, and to pass every other assertion that is
+// set.
+type HTTPProbe struct {
+	URL    string
+	Status []StatusRange
+	// Body must match the response body, of which at most MaxBodySize is
+	// read.
+	Body *regexp.Regexp
+	// JSON are assertions on the response body decoded as JSON.
+	JSON []*JSONAssertion
+	// Headers must be present in the response. A non-nil pattern must also
+	// match one of the header's values.
+	Headers map[string]*regexp.Regexp
+	Client  *http.Client
+	// OnResponse, when set, is called with every response before it is
+	// checked.
+	OnResponse func(*http.Response)
+}
+
+// Check sends a single request to the probe's URL.
+func (p *HTTPProbe) Check(ctx context.Context) error {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return Permanent(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+		resp.Body.Close()
+	}()
+	if p.OnResponse != nil {
+		p.OnResponse(resp)
+	}
+	if !p.expects(resp.StatusCode) {
+		return fmt.Errorf("%s answered %s", p.URL, resp.Status)
+	}
+	if err := p.checkHeaders(resp.Header); err != nil {
+		return fmt.Errorf("%s: %w", p.URL, err)
+	}
+	if p.Body == nil && len(p.JSON) == 0 {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize))
+	if err != nil {
+		return fmt.Errorf("%s: reading body: %w", p.URL, err)
+	}
+	if err := p.checkBody(body); err != nil {
+		return fmt.Errorf("%s: %w", p.URL, err)
+	}
+	return nil
+}
+
+func (p *HTTPProbe) expects(code int) bool {
+	if len(p.Status) == 0 {
+		return code == http.StatusOK
+	}
+	for _, r := range p.Status {
+		if r.Contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *HTTPProbe) checkHeaders(header http.Header) error {
+	for name, pattern := range p.Headers {
+		values := header.Values(name)
+		if len(values) == 0 {
+			return fmt.Errorf("header %s is missing", name)
+		}
+		if pattern == nil {
+			continue
+		}
+		matched := false
+		for _, value := range values {
+			if pattern.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("header %s %q does not match %q", name, strings.Join(values, ", "), pattern)
+		}
+	}
+	return nil
+}
+
+func (p *HTTPProbe) checkBody(body []byte) error {
+	if p.Body != nil && !p.Body.Match(body) {
+		return fmt.Errorf("body does not match %q", p.Body)
+	}
+	if len(p.JSON) == 0 {
+		return nil
+	}
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return fmt.Errorf("body is not JSON: %w", err)
+	}
+	for _, assertion := range p.JSON {
+		if err := assertion.Check(document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
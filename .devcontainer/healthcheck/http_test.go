@@ -0,0 +1,172 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPProbe(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	probe := &HTTPProbe{URL: server.URL}
+	var retries int
+	err := Wait(context.Background(), 10*time.Millisecond, probe.Check, func(err error) {
+		if !strings.Contains(err.Error(), "503 Service Unavailable") {
+			t.Errorf("unexpected failure '%v'", err)
+		}
+		retries++
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if retries != 2 {
+		t.Errorf("expected 2 retries, got %d", retries)
+	}
+}
+
+func TestHTTPProbeStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	if err := (&HTTPProbe{URL: server.URL}).Check(context.Background()); err == nil {
+		t.Errorf("expected 429 to be unhealthy by default")
+	}
+	probe := &HTTPProbe{URL: server.URL, Status: []StatusRange{{200, 200}, {429, 429}}}
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("expected 429 to be accepted, got '%v'", err)
+	}
+}
+
+func TestParseStatusRange(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected StatusRange
+		fails    bool
+	}{
+		{input: "200", expected: StatusRange{200, 200}},
+		{input: "2xx", expected: StatusRange{200, 299}},
+		{input: "4XX", expected: StatusRange{400, 499}},
+		{input: "200-204", expected: StatusRange{200, 204}},
+		{input: "204-200", fails: true},
+		{input: "6xx", fails: true},
+		{input: "99", fails: true},
+		{input: "ok", fails: true},
+	} {
+		got, err := ParseStatusRange(test.input)
+		if test.fails {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", test.input, got)
+			}
+			continue
+		}
+		if err != nil || got != test.expected {
+			t.Errorf("%q: expected %v, got %v, '%v'", test.input, test.expected, got, err)
+		}
+	}
+}
+
+func TestHTTPProbeAssertions(t *testing.T) {
+	body := `{"initialized": true, "sealed": false, "version": "1.19.0"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	json := func(expr string) *JSONAssertion {
+		assertion, err := ParseJSONAssertion(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return assertion
+	}
+	standby := []StatusRange{{200, 200}, {429, 429}}
+	for _, test := range []struct {
+		name  string
+		probe HTTPProbe
+		fails string
+	}{
+		{name: "status range", probe: HTTPProbe{Status: []StatusRange{{400, 499}}}},
+		{name: "status mismatch", probe: HTTPProbe{Status: []StatusRange{{200, 299}}}, fails: "429 Too Many Requests"},
+		{name: "body", probe: HTTPProbe{Status: standby, Body: regexp.MustCompile(`"version": "1\.\d+`)}},
+		{name: "body mismatch", probe: HTTPProbe{Status: standby, Body: regexp.MustCompile(`ok`)}, fails: "body does not match"},
+		{name: "json", probe: HTTPProbe{Status: standby, JSON: []*JSONAssertion{json(".initialized == true"), json(".sealed != true")}}},
+		{name: "json mismatch", probe: HTTPProbe{Status: standby, JSON: []*JSONAssertion{json(".sealed == true")}}, fails: ".sealed == true failed, got false"},
+		{name: "header", probe: HTTPProbe{Status: standby, Headers: map[string]*regexp.Regexp{
+			"content-type": regexp.MustCompile("^application/json"),
+		}}},
+		{name: "header present", probe: HTTPProbe{Status: standby, Headers: map[string]*regexp.Regexp{"Content-Type": nil}}},
+		{name: "header missing", probe: HTTPProbe{Status: standby, Headers: map[string]*regexp.Regexp{"X-Vault-Token": nil}}, fails: "header X-Vault-Token is missing"},
+		{name: "header mismatch", probe: HTTPProbe{Status: standby, Headers: map[string]*regexp.Regexp{
+			"Content-Type": regexp.MustCompile("text/plain"),
+		}}, fails: "does not match"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.probe.URL = server.URL
+			err := test.probe.Check(context.Background())
+			if test.fails == "" {
+				if err != nil {
+					t.Errorf("expected no error, got '%v'", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.fails) {
+				t.Errorf("expected an error containing '%s', got '%v'", test.fails, err)
+			}
+		})
+	}
+}
+
+func TestHTTPProbeNotJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	}))
+	defer server.Close()
+
+	assertion, _ := ParseJSONAssertion(".initialized")
+	probe := &HTTPProbe{URL: server.URL, JSON: []*JSONAssertion{assertion}}
+	if err := probe.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "body is not JSON") {
+		t.Errorf("expected a JSON error, got '%v'", err)
+	}
+}
+
+func TestHTTPProbeReusesConnections(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	probe := &HTTPProbe{URL: server.URL, Client: server.Client()}
+	for range 5 {
+		if err := probe.Check(context.Background()); err == nil {
+			t.Fatal("expected 503 to be unhealthy")
+		}
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("expected the unread bodies to be drained and 1 connection to be reused, got %d connections", n)
+	}
+}
@@ -0,0 +1,197 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// JSONAssertion checks a field of a JSON document, in the style of jq -e:
+//
+//	.initialized == true
+//	.replication_dr_mode != "primary"
+//	.items[0].name
+//	.["key.with.dots"] == 1
+//
+// A path without a comparison expects the field to be present and neither
+// null nor false.
+type JSONAssertion struct {
+	expr string
+	// path are the object keys, as strings, and array indexes, as ints,
+	// leading to the field.
+	path []any
+	// op is "==", "!=" or empty for a bare path.
+	op    string
+	value any
+}
+
+// ParseJSONAssertion parses an expression such as `.initialized == true`.
+// The value compared with is a JSON literal.
+func ParseJSONAssertion(expr string) (*JSONAssertion, error) {
+	a := &JSONAssertion{expr: strings.TrimSpace(expr)}
+	// The operator is only looked for after the path, as quoted keys may
+	// hold one.
+	path, rest, err := parseJSONPath(a.expr)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", expr, err)
+	}
+	a.path = path
+	rest = strings.TrimSpace(rest)
+	switch {
+	case rest == "":
+		return a, nil
+	case strings.HasPrefix(rest, "==") || strings.HasPrefix(rest, "!="):
+		a.op = rest[:2]
+	case strings.HasPrefix(rest, "="):
+		return nil, fmt.Errorf(`%q: unknown operator "=", compare with ==`, expr)
+	default:
+		return nil, fmt.Errorf("%q: unknown operator at %q, expected == or !=", expr, rest)
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(rest[2:])), &a.value); err != nil {
+		return nil, fmt.Errorf("%q: the right-hand side must be a JSON value: %w", expr, err)
+	}
+	return a, nil
+}
+
+// parseJSONPath parses the path such as .a.b[0]["c"] that expr starts with
+// and returns the rest of expr. An optional $ stands for the document.
+func parseJSONPath(expr string) ([]any, string, error) {
+	rest, hasRoot := strings.CutPrefix(expr, "$")
+	if !hasRoot && !strings.HasPrefix(rest, ".") && !strings.HasPrefix(rest, "[") {
+		return nil, "", fmt.Errorf("path must start with . or $")
+	}
+	var path []any
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".["):
+			rest = rest[1:]
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexFunc(rest, isPathEnd)
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				// A lone . is the document itself.
+				if len(path) == 0 && (rest == "" || (rest[0] != '.' && rest[0] != '[')) {
+					return path, rest, nil
+				}
+				return nil, "", fmt.Errorf("empty key in path")
+			}
+			path = append(path, key)
+			rest = rest[end:]
+		case rest[0] == '[':
+			segment, n, err := parseJSONIndex(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			path = append(path, segment)
+			rest = rest[n:]
+		default:
+			return path, rest, nil
+		}
+	}
+	return path, rest, nil
+}
+
+// parseJSONIndex parses a segment such as [0] or ["key"] at the start of
+// s and returns it along with its length.
+func parseJSONIndex(s string) (any, int, error) {
+	if strings.HasPrefix(s, `["`) {
+		quoted, err := strconv.QuotedPrefix(s[1:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("unterminated key in %s", s)
+		}
+		n := 1 + len(quoted)
+		if !strings.HasPrefix(s[n:], "]") {
+			return nil, 0, fmt.Errorf("expected ] after %s", quoted)
+		}
+		key, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid key %s: %w", quoted, err)
+		}
+		return key, n + 1, nil
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return nil, 0, fmt.Errorf("unterminated [ in path")
+	}
+	segment := strings.TrimSpace(s[1:end])
+	index, err := strconv.Atoi(segment)
+	if err != nil {
+		return nil, 0, fmt.Errorf("[%s] must be an index or a quoted key", segment)
+	}
+	return index, end + 1, nil
+}
+
+// isPathEnd reports whether r ends a key written after a dot.
+func isPathEnd(r rune) bool {
+	return r == '.' || r == '[' || r == '=' || r == '!' || unicode.IsSpace(r)
+}
+
+// Check evaluates the assertion against a document decoded with
+// encoding/json.
+func (a *JSONAssertion) Check(document any) error {
+	value, found := a.lookup(document)
+	switch a.op {
+	case "==":
+		if !found || !reflect.DeepEqual(value, a.value) {
+			return fmt.Errorf("%s failed, got %s", a.expr, describeJSON(value, found))
+		}
+	case "!=":
+		if found && reflect.DeepEqual(value, a.value) {
+			return fmt.Errorf("%s failed", a.expr)
+		}
+	default:
+		if !found || value == nil || value == false {
+			return fmt.Errorf("%s failed, got %s", a.expr, describeJSON(value, found))
+		}
+	}
+	return nil
+}
+
+func (a *JSONAssertion) String() string {
+	return a.expr
+}
+
+// lookup follows the path of a through document.
+func (a *JSONAssertion) lookup(document any) (any, bool) {
+	value := document
+	for _, segment := range a.path {
+		switch segment := segment.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if value, ok = object[segment]; !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := value.([]any)
+			if segment < 0 {
+				segment += len(array)
+			}
+			if !ok || segment < 0 || segment >= len(array) {
+				return nil, false
+			}
+			value = array[segment]
+		}
+	}
+	return value, true
+}
+
+func describeJSON(value any, found bool) string {
+	if !found {
+		return "nothing"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
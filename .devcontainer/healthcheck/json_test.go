@@ -0,0 +1,87 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONAssertion(t *testing.T) {
+	var document any
+	if err := json.Unmarshal([]byte(`{
+		"initialized": true,
+		"sealed": false,
+		"standby": null,
+		"version": "1.19.0",
+		"cluster": {"nodes": [{"name": "a", "ready": true}, {"name": "b", "ready": false}]},
+		"key.with.dots": 1,
+		"a==b": 2,
+		"x]y": 3
+	}`), &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		expr   string
+		passes bool
+	}{
+		{expr: ".initialized == true", passes: true},
+		{expr: "$.initialized==true", passes: true},
+		{expr: ".initialized", passes: true},
+		{expr: ".sealed", passes: false},
+		{expr: ".standby", passes: false},
+		{expr: ".missing", passes: false},
+		{expr: ".missing != true", passes: true},
+		{expr: ".sealed == false", passes: true},
+		{expr: `.version == "1.19.0"`, passes: true},
+		{expr: `.version != "1.19.0"`, passes: false},
+		{expr: ".cluster.nodes[0].ready == true", passes: true},
+		{expr: ".cluster.nodes[-1].ready == true", passes: false},
+		{expr: `.cluster.nodes[1]["name"] == "b"`, passes: true},
+		{expr: ".cluster.nodes[2].ready", passes: false},
+		{expr: `.["key.with.dots"] == 1`, passes: true},
+		{expr: `.["a==b"] == 2`, passes: true},
+		{expr: `.["a==b"] != 2`, passes: false},
+		{expr: `.["x]y"]==3`, passes: true},
+		{expr: `.version == "a==b"`, passes: false},
+		{expr: ". != null", passes: true},
+	} {
+		assertion, err := ParseJSONAssertion(test.expr)
+		if err != nil {
+			t.Errorf("%q: %v", test.expr, err)
+			continue
+		}
+		if err := assertion.Check(document); (err == nil) != test.passes {
+			t.Errorf("%q: expected passing to be %t, got '%v'", test.expr, test.passes, err)
+		}
+	}
+}
+
+func TestParseJSONAssertionInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"initialized == true",
+		".initialized == yes",
+		".a..b",
+		".items[first]",
+		".items[0",
+		`.["key"`,
+		`.["key"x]`,
+	} {
+		if _, err := ParseJSONAssertion(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+
+	for expr, message := range map[string]string{
+		".ready = true":           `unknown operator "=", compare with ==`,
+		".ready < 1":              `unknown operator at "< 1"`,
+		".cluster.nodes | length": `unknown operator at "| length"`,
+		`.["a==b"] = 1`:           `unknown operator "="`,
+		".ready == maybe":         "the right-hand side must be a JSON value",
+	} {
+		_, err := ParseJSONAssertion(expr)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%q: expected an error containing '%s', got '%v'", expr, message, err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+)
+
+// TCPProbe expects Address, a host:port, to accept a TCP connection.
+type TCPProbe struct {
+	Address string
+	Dialer  *net.Dialer
+}
+
+// Check opens and closes a single connection to the probe's address.
+func (p *TCPProbe) Check(ctx context.Context) error {
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		return Permanent(err)
+	}
+	dialer := p.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,27 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestTCPProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	probe := &TCPProbe{Address: address}
+	if err := probe.Check(context.Background()); err != nil {
+		t.Errorf("expected the listener to accept, got '%v'", err)
+	}
+	listener.Close()
+	if err := probe.Check(context.Background()); err == nil || IsPermanent(err) {
+		t.Errorf("expected a retryable failure once closed, got '%v'", err)
+	}
+	if err := (&TCPProbe{Address: "localhost"}).Check(context.Background()); !IsPermanent(err) {
+		t.Errorf("expected an address without port to be permanent, got '%v'", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"scripts/healthcheck"
+)
+
+const (
+	statusHealthy     = "healthy"
+	statusTimeout     = "timeout"
+	statusError       = "error"
+	statusInterrupted = "interrupted"
+)
+
+// Result is the outcome of waiting for a target.
+type Result struct {
+	Target Target
+	// Healthy reports whether the target became healthy before its timeout.
+	Healthy bool
+	// Elapsed is the time until the target became healthy or was given up.
+	Elapsed  time.Duration
+	Attempts int
+	// Latencies are the durations of the attempts, in order.
+	Latencies []time.Duration
+	// LastStatusCode is the status code of the last response, 0 when no
+	// request got an answer.
+	LastStatusCode int
+	// Err is the last failure of a target that did not become healthy.
+	Err error
+}
+
+// Status is healthy, timeout when the target was not healthy before its
+// deadline, error when it could not be probed at all, or interrupted when
+// the wait was cancelled before the deadline, e.g. by SIGINT.
+func (r Result) Status() string {
+	switch {
+	case r.Healthy:
+		return statusHealthy
+	case errors.Is(r.Err, context.Canceled):
+		return statusInterrupted
+	case healthcheck.IsPermanent(r.Err):
+		return statusError
+	default:
+		return statusTimeout
+	}
+}
+
+// CheckAll waits for every target concurrently and returns their results
+// in the order of targets.
+func CheckAll(ctx context.Context, targets []Target) []Result {
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = Check(ctx, target)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Check polls target until it is healthy or its timeout expires.
+func Check(ctx context.Context, target Target) Result {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(target.Timeout))
+	defer cancel()
+	logger := log.With().Str("target", target.Name).Logger()
+	kind, _ := target.kind()
+	logger.Info().Str(kind, target.address()).Msg("Checking if the service is up...")
+
+	result := Result{Target: target}
+	probe, err := target.probe(func(resp *http.Response) {
+		result.LastStatusCode = resp.StatusCode
+	})
+	if err != nil {
+		result.Err = healthcheck.Permanent(err)
+		logger.Error().Err(err).Msg("Service cannot be probed.")
+		return result
+	}
+	check := func(ctx context.Context) error {
+		result.Attempts++
+		start := time.Now()
+		err := probe(ctx)
+		result.Latencies = append(result.Latencies, time.Since(start))
+		return err
+	}
+	start := time.Now()
+	err = target.retry().Wait(ctx, check, func(err error) {
+		logger.Info().Err(err).Msg("Service not ready yet. Retrying...")
+	})
+	result.Elapsed = time.Since(start)
+	if err != nil {
+		result.Err = err
+		switch result.Status() {
+		case statusError:
+			logger.Error().Err(err).Msg("Service cannot be probed.")
+		case statusInterrupted:
+			logger.Warn().Err(err).Msg("Interrupted before the service was up.")
+		default:
+			logger.Error().Err(err).Msg("Timeout reached. Service is not responding.")
+		}
+		return result
+	}
+	result.Healthy = true
+	logger.Info().Dur("after", result.Elapsed).Msg("Service is up and running!")
+	return result
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckAll(t *testing.T) {
+	var calls atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	targets := []Target{
+		{Name: "flaky", URL: flaky.URL, Settings: Settings{Timeout: Duration(time.Second), Interval: Duration(10 * time.Millisecond)}},
+		{Name: "down", URL: down.URL, Settings: Settings{Timeout: Duration(50 * time.Millisecond), Interval: Duration(10 * time.Millisecond)}},
+	}
+	results := CheckAll(context.Background(), targets)
+
+	if !results[0].Healthy || results[0].Attempts != 2 || len(results[0].Latencies) != 2 || results[0].LastStatusCode != http.StatusOK {
+		t.Errorf("expected flaky to be healthy after 2 attempts, got %+v", results[0])
+	}
+	if results[1].Healthy || results[1].LastStatusCode != http.StatusBadGateway || !strings.Contains(results[1].Err.Error(), "502 Bad Gateway") {
+		t.Errorf("expected down to fail with its last status, got %+v", results[1])
+	}
+
+	var out bytes.Buffer
+	printReport(&out, results)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "flaky") || !strings.Contains(lines[1], "healthy") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[2], "down") || !strings.Contains(lines[2], "timeout") || strings.Contains(lines[2], "\n") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestCheckInterrupted(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	target := Target{Name: "down", URL: down.URL, Settings: Settings{Timeout: Duration(time.Minute), Interval: Duration(10 * time.Millisecond)}}
+	result := Check(ctx, target)
+
+	if result.Status() != statusInterrupted {
+		t.Errorf("expected an interrupted check, got %s: %v", result.Status(), result.Err)
+	}
+	if code := exitCode([]Result{result}); code != exitInterrupted {
+		t.Errorf("expected exit code %d, got %d", exitInterrupted, code)
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	var calls atomic.Int32
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) < 3 {
+			w.Write([]byte(`{"initialized": false}`))
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"initialized": true}`))
+	}))
+	defer vault.Close()
+
+	target := Target{
+		Name:     "vault",
+		URL:      vault.URL,
+		Status:   StatusCodes{{Min: 200, Max: 200}, {Min: 429, Max: 429}},
+		JSON:     []string{".initialized == true"},
+		Settings: Settings{Timeout: Duration(time.Second), Interval: Duration(10 * time.Millisecond)},
+	}
+	result := Check(context.Background(), target)
+	if !result.Healthy || result.Attempts != 3 || result.LastStatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected vault to be healthy once initialized, got %+v", result)
+	}
+
+	target.JSON = []string{"initialized"}
+	result = Check(context.Background(), target)
+	if result.Status() != statusError || result.Attempts != 0 {
+		t.Errorf("expected an invalid assertion to be an error without attempts, got %+v", result)
+	}
+}
+
+func TestCheckKinds(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	settings := Settings{Timeout: Duration(100 * time.Millisecond), Interval: Duration(10 * time.Millisecond)}
+	targets := []Target{
+		{Name: "tcp", TCP: listener.Addr().String(), Settings: settings},
+		{Name: "dns", DNS: "localhost", Settings: settings},
+		{Name: "exec", Exec: []string{"true"}, Settings: settings},
+		{Name: "closed", TCP: closed.Addr().String(), Settings: settings},
+		{Name: "failing", Exec: []string{"false"}, Settings: settings},
+	}
+	results := CheckAll(context.Background(), targets)
+	for _, result := range results[:3] {
+		if !result.Healthy {
+			t.Errorf("expected %s to be healthy, got '%v'", result.Target.Name, result.Err)
+		}
+	}
+	for _, result := range results[3:] {
+		if result.Status() != statusTimeout || result.Attempts < 2 {
+			t.Errorf("expected %s to be retried until its timeout, got %+v", result.Target.Name, result)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"scripts/healthcheck"
+)
+
+const (
+	defaultTimeout        = 1 * time.Minute
+	defaultInterval       = 10 * time.Second
+	defaultRequestTimeout = 5 * time.Second
+)
+
+// Config lists the targets healthz waits for. Its settings apply to targets
+// that do not set their own.
+//
+//	timeout: 2m
+//	interval: 1s
+//	backoff: 2
+//	max_interval: 15s
+//	jitter: 0.2
+//	targets:
+//	  - name: vault
+//	    url: http://127.0.0.1:8200/v1/sys/health
+//	    status: [200, 429]
+//	    json: [".initialized == true"]
+//	  - url: http://localhost:8080/healthz
+//	    timeout: 30s
+//	    request_timeout: 2s
+//	    status: ["2xx", "300-304"]
+//	    body: ok
+//	    headers:
+//	      Content-Type: ^application/json
+//	  - name: postgres
+//	    tcp: postgres:5432
+//	  - dns: vault.alpine-net
+//	  - grpc: localhost:9090
+//	    grpc_service: api
+//	  - name: migrations
+//	    exec: [pg_isready, -h, postgres]
+//
+// JSON works as well, being a subset of YAML.
+type Config struct {
+	Settings `yaml:",inline"`
+	Targets  []Target `yaml:"targets"`
+}
+
+// Settings time the checks of a target. Zero values are inherited from the
+// config, then from the command line.
+type Settings struct {
+	// Timeout is the deadline for the target to become healthy.
+	Timeout Duration `yaml:"timeout"`
+	// Interval is the time before the first retry.
+	Interval Duration `yaml:"interval"`
+	// Backoff multiplies the interval after every retry, up to
+	// MaxInterval. 1 keeps it fixed.
+	Backoff     float64  `yaml:"backoff"`
+	MaxInterval Duration `yaml:"max_interval"`
+	// Jitter randomizes every interval by up to this fraction of it.
+	Jitter float64 `yaml:"jitter"`
+	// RequestTimeout bounds every single attempt: a request, connection,
+	// lookup or command.
+	RequestTimeout Duration `yaml:"request_timeout"`
+}
+
+// defaultSettings are used when neither the config nor the command line
+// set a value.
+var defaultSettings = Settings{
+	Timeout:        Duration(defaultTimeout),
+	Interval:       Duration(defaultInterval),
+	Backoff:        1,
+	RequestTimeout: Duration(defaultRequestTimeout),
+}
+
+// inherit fills the zero values of s from parent.
+func (s Settings) inherit(parent Settings) Settings {
+	if s.Timeout == 0 {
+		s.Timeout = parent.Timeout
+	}
+	if s.Interval == 0 {
+		s.Interval = parent.Interval
+	}
+	if s.Backoff == 0 {
+		s.Backoff = parent.Backoff
+	}
+	if s.MaxInterval == 0 {
+		s.MaxInterval = parent.MaxInterval
+	}
+	if s.Jitter == 0 {
+		s.Jitter = parent.Jitter
+	}
+	if s.RequestTimeout == 0 {
+		s.RequestTimeout = parent.RequestTimeout
+	}
+	return s
+}
+
+func (s Settings) validate() error {
+	switch {
+	case s.Timeout < 0 || s.Interval < 0 || s.MaxInterval < 0 || s.RequestTimeout < 0:
+		return fmt.Errorf("durations must not be negative")
+	case s.Backoff != 0 && s.Backoff < 1:
+		return fmt.Errorf("backoff %g must be at least 1", s.Backoff)
+	case s.Jitter < 0 || s.Jitter > 1:
+		return fmt.Errorf("jitter %g must be between 0 and 1", s.Jitter)
+	case s.MaxInterval != 0 && s.MaxInterval < s.Interval:
+		return fmt.Errorf("max_interval %s is shorter than interval %s", time.Duration(s.MaxInterval), time.Duration(s.Interval))
+	}
+	return nil
+}
+
+// retry returns how the checks of a target with s are spaced.
+func (s Settings) retry() healthcheck.Retry {
+	return healthcheck.Retry{
+		Interval:       time.Duration(s.Interval),
+		Multiplier:     s.Backoff,
+		MaxInterval:    time.Duration(s.MaxInterval),
+		Jitter:         s.Jitter,
+		AttemptTimeout: time.Duration(s.RequestTimeout),
+	}
+}
+
+// Duration is a time.Duration written as a string such as "30s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// LoadConfig reads and validates the config at path. Targets inherit the
+// settings the config leaves unset from defaults.
+func LoadConfig(path string, defaults Settings) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(content, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses a config in YAML or JSON, validates it and fills in
+// the settings of every target.
+func ParseConfig(content []byte, defaults Settings) (*Config, error) {
+	var c Config
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	c.applyDefaults(defaults)
+	return &c, nil
+}
+
+// singleTarget is the config of the -s flag.
+func singleTarget(serviceURL string, defaults Settings) (*Config, error) {
+	c := &Config{Targets: []Target{{URL: serviceURL}}}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	c.applyDefaults(defaults)
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	if err := c.Settings.validate(); err != nil {
+		return err
+	}
+	if len(c.Targets) == 0 {
+		return fmt.Errorf("no targets defined")
+	}
+	names := make(map[string]bool, len(c.Targets))
+	for i, t := range c.Targets {
+		if _, err := t.probe(nil); err != nil {
+			return fmt.Errorf("targets[%d]: %w", i, err)
+		}
+		if err := t.Settings.validate(); err != nil {
+			return fmt.Errorf("targets[%d]: %w", i, err)
+		}
+		name := t.Name
+		if name == "" {
+			name = t.address()
+		}
+		if names[name] {
+			return fmt.Errorf("targets[%d]: duplicate target %q", i, name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// applyDefaults fills in the name and settings of every target. An
+// interval longer than the timeout is allowed and leaves time for a single
+// attempt.
+func (c *Config) applyDefaults(defaults Settings) {
+	settings := c.Settings.inherit(defaults).inherit(defaultSettings)
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		if t.Name == "" {
+			t.Name = t.address()
+		}
+		t.Settings = t.Settings.inherit(settings)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	content := `
+timeout: 2m
+interval: 5s
+targets:
+  - name: vault
+    url: http://127.0.0.1:8200/v1/sys/health
+    status: [200, 429]
+  - url: http://localhost:8080/healthz
+    timeout: 30s
+`
+	config, err := ParseConfig([]byte(content), Settings{})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if len(config.Targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(config.Targets))
+	}
+	vault, web := config.Targets[0], config.Targets[1]
+	if vault.Name != "vault" || len(vault.Status) != 2 || time.Duration(vault.Timeout) != 2*time.Minute || time.Duration(vault.Interval) != 5*time.Second {
+		t.Errorf("unexpected vault target %+v", vault)
+	}
+	if web.Name != web.URL || time.Duration(web.Timeout) != 30*time.Second || time.Duration(web.Interval) != 5*time.Second {
+		t.Errorf("unexpected web target %+v", web)
+	}
+}
+
+func TestParseConfigAssertions(t *testing.T) {
+	content := `
+targets:
+  - url: http://127.0.0.1:8200/v1/sys/health
+    status: [200, "4xx", "500-503"]
+    body: "a.b"
+    json: [".initialized == true", ".sealed"]
+    headers:
+      Content-Type: ^application/json
+      X-Request-Id: ""
+`
+	config, err := ParseConfig([]byte(content), Settings{})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	probe, err := config.Targets[0].httpProbe()
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := StatusCodes{{Min: 200, Max: 200}, {Min: 400, Max: 499}, {Min: 500, Max: 503}}
+	if !slices.Equal(probe.Status, expected) {
+		t.Errorf("expected status %v, got %v", expected, probe.Status)
+	}
+	if probe.Body == nil || probe.Body.MatchString("axb") || !probe.Body.MatchString("xa.bx") {
+		t.Errorf("expected body to match the literal substring, got %v", probe.Body)
+	}
+	if len(probe.JSON) != 2 || len(probe.Headers) != 2 || probe.Headers["X-Request-Id"] != nil {
+		t.Errorf("unexpected probe %+v", probe)
+	}
+}
+
+func TestParseConfigKinds(t *testing.T) {
+	content := `
+targets:
+  - tcp: postgres:5432
+  - dns: vault.alpine-net
+  - grpc: localhost:9090
+    grpc_service: api
+  - name: migrations
+    exec: [pg_isready, -h, postgres]
+`
+	config, err := ParseConfig([]byte(content), Settings{})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	expected := []struct{ kind, name string }{
+		{kindTCP, "postgres:5432"},
+		{kindDNS, "vault.alpine-net"},
+		{kindGRPC, "localhost:9090"},
+		{kindExec, "migrations"},
+	}
+	for i, target := range config.Targets {
+		kind, err := target.kind()
+		if err != nil || kind != expected[i].kind || target.Name != expected[i].name {
+			t.Errorf("targets[%d]: expected %s %q, got %s %q, '%v'", i, expected[i].kind, expected[i].name, kind, target.Name, err)
+		}
+	}
+}
+
+func TestLoadJSONConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	content := `{"targets": [{"name": "web", "url": "https://example.com", "interval": "1s"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	config, err := LoadConfig(path, Settings{})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	target := config.Targets[0]
+	if time.Duration(target.Timeout) != defaultTimeout || time.Duration(target.Interval) != time.Second {
+		t.Errorf("unexpected target %+v", target)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := map[string]string{
+		"no targets":          `targets: []`,
+		"unknown field":       "targets:\n  - url: http://a\n    method: POST\n",
+		"relative url":        "targets:\n  - url: /healthz\n",
+		"invalid status":      "targets:\n  - url: http://a\n    status: [42]\n",
+		"invalid range":       "targets:\n  - url: http://a\n    status: [299-200]\n",
+		"invalid body regex":  "targets:\n  - url: http://a\n    body_regex: \"(\"\n",
+		"body and regex":      "targets:\n  - url: http://a\n    body: ok\n    body_regex: ok\n",
+		"invalid json":        "targets:\n  - url: http://a\n    json: [initialized]\n",
+		"invalid header":      "targets:\n  - url: http://a\n    headers: {Content-Type: \"[\"}\n",
+		"no probe":            "targets:\n  - name: a\n",
+		"two probes":          "targets:\n  - url: http://a\n    tcp: a:80\n",
+		"tcp without port":    "targets:\n  - tcp: postgres\n",
+		"grpc without host":   "targets:\n  - grpc: :9090\n",
+		"dns with port":       "targets:\n  - dns: vault:8200\n",
+		"status on tcp":       "targets:\n  - tcp: a:80\n    status: [200]\n",
+		"grpc_service on url": "targets:\n  - url: http://a\n    grpc_service: api\n",
+		"invalid duration":    "targets:\n  - url: http://a\n    timeout: soon\n",
+		"duplicate":           "targets:\n  - url: http://a\n  - name: http://a\n    url: http://b\n",
+		"backoff below 1":     "backoff: 0.5\ntargets:\n  - url: http://a\n",
+		"jitter above 1":      "targets:\n  - url: http://a\n    jitter: 1.5\n",
+		"max below interval":  "interval: 10s\nmax_interval: 1s\ntargets:\n  - url: http://a\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseConfig([]byte(content), Settings{}); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	content := `
+interval: 2s
+backoff: 2
+targets:
+  - name: own
+    url: http://a
+    interval: 1s
+    request_timeout: 1s
+  - name: inherited
+    url: http://b
+`
+	flags := Settings{Timeout: Duration(5 * time.Minute), Interval: Duration(time.Hour), MaxInterval: Duration(30 * time.Second)}
+	config, err := ParseConfig([]byte(content), flags)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	own, inherited := config.Targets[0].Settings, config.Targets[1].Settings
+	expected := Settings{
+		Timeout:        Duration(5 * time.Minute),
+		Interval:       Duration(time.Second),
+		Backoff:        2,
+		MaxInterval:    Duration(30 * time.Second),
+		RequestTimeout: Duration(time.Second),
+	}
+	if own != expected {
+		t.Errorf("expected %+v, got %+v", expected, own)
+	}
+	expected.Interval = Duration(2 * time.Second)
+	expected.RequestTimeout = Duration(defaultRequestTimeout)
+	if inherited != expected {
+		t.Errorf("expected %+v, got %+v", expected, inherited)
+	}
+
+	retry := inherited.retry()
+	if retry.Delay(0) != 2*time.Second || retry.Delay(1) != 4*time.Second || retry.Delay(5) != 30*time.Second || retry.AttemptTimeout != defaultRequestTimeout {
+		t.Errorf("unexpected retry %+v", retry)
+	}
+}
+
+func TestTimeoutBelowDefaultInterval(t *testing.T) {
+	// healthz -s http://a -timeout 5s, with -interval left at its default.
+	flags := Settings{Timeout: Duration(5 * time.Second), Interval: Duration(defaultInterval)}
+	c, err := singleTarget("http://a", flags)
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if target := c.Targets[0]; target.Timeout != flags.Timeout || target.Interval != defaultSettings.Interval {
+		t.Errorf("unexpected settings %+v", target.Settings)
+	}
+
+	c, err = ParseConfig([]byte("targets:\n  - url: http://a\n    timeout: 5s\n"), Settings{})
+	if err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	if target := c.Targets[0]; target.Timeout != Duration(5*time.Second) || target.Interval != defaultSettings.Interval {
+		t.Errorf("unexpected settings %+v", target.Settings)
+	}
+}
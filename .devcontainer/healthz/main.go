@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// log zerolog.Logger
+var once sync.Once
+
+func init() {
+	once.Do(func() {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		if os.Getenv("DEBUG") != "" {
+			zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		}
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: zerolog.TimeFormatUnix})
+	})
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run checks the targets given on the command line and returns the exit
+// code.
+func run() int {
+	service := flag.String("s", "", "URL of a single service to check")
+	configPath := flag.String("config", "", "YAML or JSON file listing the services to check")
+	output := flag.String("output", outputText, "Result format on stdout: text or json")
+	// The timing flags apply to targets the config does not time itself.
+	var defaults Settings
+	flag.DurationVar((*time.Duration)(&defaults.Timeout), "timeout", defaultTimeout, "Deadline for every service to become healthy")
+	flag.DurationVar((*time.Duration)(&defaults.Interval), "interval", defaultInterval, "Time before the first retry")
+	flag.Float64Var(&defaults.Backoff, "backoff", 1, "Multiply the interval by this after every retry (1 keeps it fixed)")
+	flag.DurationVar((*time.Duration)(&defaults.MaxInterval), "max-interval", 0, "Longest interval between retries with -backoff (0 for no limit)")
+	flag.Float64Var(&defaults.Jitter, "jitter", 0, "Randomize every interval by up to this fraction of it, e.g. 0.2")
+	flag.DurationVar((*time.Duration)(&defaults.RequestTimeout), "request-timeout", defaultRequestTimeout, "Timeout of every single request, connection, lookup or command")
+	flag.Parse()
+
+	var config *Config
+	err := defaults.validate()
+	switch {
+	case err != nil:
+	case *output != outputText && *output != outputJSON:
+		err = fmt.Errorf("unknown output %q, expected %s or %s", *output, outputText, outputJSON)
+	case *configPath != "":
+		config, err = LoadConfig(*configPath, defaults)
+	case *service != "":
+		config, err = singleTarget(*service, defaults)
+	default:
+		err = fmt.Errorf("usage: ./healthz -s <service-url> | -config <file>")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid configuration")
+		if *output == outputJSON {
+			writeJSON(os.Stdout, nil, err)
+		}
+		return exitInvalidConfig
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results := CheckAll(ctx, config.Targets)
+	switch {
+	case *output == outputJSON:
+		if err := writeJSON(os.Stdout, results, nil); err != nil {
+			log.Error().Err(err).Msg("Error writing the report")
+		}
+	case len(results) > 1:
+		printReport(os.Stdout, results)
+	}
+	return exitCode(results)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes of healthz, so that scripts can tell why it failed.
+const (
+	exitHealthy       = 0
+	exitTimeout       = 1
+	exitInvalidConfig = 2
+	exitProbeError    = 3
+	exitInterrupted   = 4
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// exitCode returns the exit code for results: an interruption outweighs a
+// probe error, which outweighs a timeout, which outweighs healthy targets.
+func exitCode(results []Result) int {
+	code := exitHealthy
+	for _, r := range results {
+		switch r.Status() {
+		case statusInterrupted:
+			return exitInterrupted
+		case statusError:
+			code = exitProbeError
+		case statusTimeout:
+			if code == exitHealthy {
+				code = exitTimeout
+			}
+		}
+	}
+	return code
+}
+
+// exitStatus names an exit code in the JSON report.
+func exitStatus(code int) string {
+	switch code {
+	case exitHealthy:
+		return statusHealthy
+	case exitTimeout:
+		return statusTimeout
+	case exitInvalidConfig:
+		return "invalid_config"
+	case exitInterrupted:
+		return statusInterrupted
+	default:
+		return statusError
+	}
+}
+
+// Report is the document written by -output json.
+type Report struct {
+	Status   string         `json:"status"`
+	ExitCode int            `json:"exit_code"`
+	Error    string         `json:"error,omitempty"`
+	Targets  []TargetReport `json:"targets"`
+}
+
+// TargetReport is the outcome of a single target in a Report.
+type TargetReport struct {
+	Name string `json:"name"`
+	// Kind is the kind of probe: url, tcp, dns, grpc or exec.
+	Kind string `json:"kind"`
+	// URL is the URL of url targets, Address what other probes check.
+	URL            string    `json:"url,omitempty"`
+	Address        string    `json:"address,omitempty"`
+	Status         string    `json:"status"`
+	ElapsedMS      float64   `json:"elapsed_ms"`
+	Attempts       int       `json:"attempts"`
+	LatenciesMS    []float64 `json:"latencies_ms"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// newReport builds the report of results. err, when set, is the reason no
+// target was checked.
+func newReport(results []Result, err error) Report {
+	code := exitCode(results)
+	report := Report{Targets: make([]TargetReport, len(results))}
+	if err != nil {
+		code = exitInvalidConfig
+		report.Error = err.Error()
+	}
+	report.ExitCode = code
+	report.Status = exitStatus(code)
+	for i, r := range results {
+		target := TargetReport{
+			Name:           r.Target.Name,
+			URL:            r.Target.URL,
+			Status:         r.Status(),
+			ElapsedMS:      milliseconds(r.Elapsed),
+			Attempts:       r.Attempts,
+			LatenciesMS:    make([]float64, len(r.Latencies)),
+			LastStatusCode: r.LastStatusCode,
+		}
+		target.Kind, _ = r.Target.kind()
+		if target.URL == "" {
+			target.Address = r.Target.address()
+		}
+		for j, latency := range r.Latencies {
+			target.LatenciesMS[j] = milliseconds(latency)
+		}
+		if r.Err != nil {
+			target.LastError = r.Err.Error()
+		}
+		report.Targets[i] = target
+	}
+	return report
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeJSON writes the report of results as indented JSON.
+func writeJSON(w io.Writer, results []Result, err error) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(newReport(results, err))
+}
+
+// printReport writes a table of results, one row per target.
+func printReport(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tSTATUS\tAFTER\tATTEMPTS\tERROR")
+	for _, r := range results {
+		var msg string
+		if r.Err != nil {
+			msg = strings.ReplaceAll(r.Err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", r.Target.Name, r.Status(), r.Elapsed.Round(time.Millisecond), r.Attempts, msg)
+	}
+	tw.Flush()
+}
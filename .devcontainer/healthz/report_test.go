@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"scripts/healthcheck"
+)
+
+func TestExitCode(t *testing.T) {
+	healthy := Result{Healthy: true}
+	timeout := Result{Err: errors.New("context deadline exceeded")}
+	probeError := Result{Err: healthcheck.Permanent(errors.New("invalid request"))}
+	interrupted := Result{Err: errors.Join(context.Canceled, errors.New("connection refused"))}
+
+	tests := []struct {
+		name     string
+		results  []Result
+		expected int
+	}{
+		{"healthy", []Result{healthy, healthy}, exitHealthy},
+		{"timeout", []Result{healthy, timeout}, exitTimeout},
+		{"probe error", []Result{timeout, probeError, healthy}, exitProbeError},
+		{"probe error after a timeout", []Result{probeError, timeout}, exitProbeError},
+		{"interrupted", []Result{healthy, probeError, interrupted}, exitInterrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := exitCode(tt.results); code != tt.expected {
+				t.Errorf("expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	results := []Result{
+		{
+			Target:         Target{Name: "web", URL: "http://web"},
+			Healthy:        true,
+			Elapsed:        1500 * time.Millisecond,
+			Attempts:       2,
+			Latencies:      []time.Duration{250 * time.Millisecond, 1250 * time.Microsecond},
+			LastStatusCode: 200,
+		},
+		{
+			Target:         Target{Name: "vault", URL: "http://vault"},
+			Elapsed:        time.Second,
+			Attempts:       1,
+			Latencies:      []time.Duration{time.Millisecond},
+			LastStatusCode: 503,
+			Err:            errors.New("http://vault answered 503 Service Unavailable"),
+		},
+		{
+			Target:  Target{Name: "postgres", TCP: "postgres:5432"},
+			Elapsed: time.Second,
+		},
+	}
+
+	var out bytes.Buffer
+	if err := writeJSON(&out, results, nil); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	var report Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("expected valid JSON, got '%v':\n%s", err, out.String())
+	}
+	if report.Status != statusTimeout || report.ExitCode != exitTimeout {
+		t.Errorf("expected a timeout, got %s (%d)", report.Status, report.ExitCode)
+	}
+	web, vault, postgres := report.Targets[0], report.Targets[1], report.Targets[2]
+	if web.Status != statusHealthy || web.ElapsedMS != 1500 || web.LatenciesMS[1] != 1.25 || web.LastError != "" {
+		t.Errorf("unexpected web report %+v", web)
+	}
+	if postgres.Kind != kindTCP || postgres.Address != "postgres:5432" || postgres.URL != "" || web.Kind != kindHTTP || web.Address != "" {
+		t.Errorf("unexpected kinds or addresses %+v, %+v", web, postgres)
+	}
+	if vault.LastStatusCode != 503 || vault.LastError != "http://vault answered 503 Service Unavailable" {
+		t.Errorf("unexpected vault report %+v", vault)
+	}
+}
+
+func TestWriteJSONInvalidConfig(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeJSON(&out, nil, errors.New("no targets defined")); err != nil {
+		t.Fatalf("expected no error, got '%v'", err)
+	}
+	var report Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("expected valid JSON, got '%v'", err)
+	}
+	if report.ExitCode != exitInvalidConfig || report.Status != "invalid_config" || report.Error != "no targets defined" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Targets == nil {
+		t.Errorf("expected an empty list of targets, got null")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"scripts/healthcheck"
+)
+
+// Probe kinds, named after the field of Target that selects them.
+const (
+	kindHTTP = "url"
+	kindTCP  = "tcp"
+	kindDNS  = "dns"
+	kindGRPC = "grpc"
+	kindExec = "exec"
+)
+
+// Target is a single service to wait for, probed by exactly one of URL,
+// TCP, DNS, GRPC and Exec.
+type Target struct {
+	// Name identifies the target in logs and the report, its address by
+	// default.
+	Name string `yaml:"name"`
+	// URL is an http or https URL to GET. The response must pass the
+	// assertions below.
+	URL string `yaml:"url"`
+	// Status are the status codes that count as healthy, 200 by default:
+	// codes such as 200, classes such as "2xx" or ranges such as "200-299".
+	Status StatusCodes `yaml:"status"`
+	// Body is a substring the response body must contain.
+	Body string `yaml:"body"`
+	// BodyRegex is a regular expression the response body must match.
+	BodyRegex string `yaml:"body_regex"`
+	// JSON are assertions on the response body decoded as JSON, such as
+	// ".initialized == true".
+	JSON []string `yaml:"json"`
+	// Headers are headers the response must have. A value other than empty
+	// is a regular expression one of the header's values must match.
+	Headers map[string]string `yaml:"headers"`
+	// TCP is a host:port that must accept connections.
+	TCP string `yaml:"tcp"`
+	// DNS is a host name that must resolve.
+	DNS string `yaml:"dns"`
+	// GRPC is the host:port of a server whose standard health service,
+	// grpc.health.v1.Health, must report SERVING.
+	GRPC string `yaml:"grpc"`
+	// GRPCService is the service asked about, the whole server by default.
+	GRPCService string `yaml:"grpc_service"`
+	// GRPCTLS connects to the gRPC server with TLS.
+	GRPCTLS bool `yaml:"grpc_tls"`
+	// Exec is a command, run without a shell, that must exit with status 0.
+	Exec     []string `yaml:"exec"`
+	Settings `yaml:",inline"`
+}
+
+// kind returns the kind of probe t selects, or an error unless it selects
+// exactly one.
+func (t Target) kind() (string, error) {
+	var kinds []string
+	for kind, set := range map[string]bool{
+		kindHTTP: t.URL != "",
+		kindTCP:  t.TCP != "",
+		kindDNS:  t.DNS != "",
+		kindGRPC: t.GRPC != "",
+		kindExec: len(t.Exec) > 0,
+	} {
+		if set {
+			kinds = append(kinds, kind)
+		}
+	}
+	switch len(kinds) {
+	case 0:
+		return "", fmt.Errorf("one of url, tcp, dns, grpc or exec is required")
+	case 1:
+		return kinds[0], nil
+	default:
+		slices.Sort(kinds)
+		return "", fmt.Errorf("only one of %s may be set", strings.Join(kinds, ", "))
+	}
+}
+
+// address returns what the probe of t checks: its URL, host, host:port or
+// command line.
+func (t Target) address() string {
+	switch {
+	case t.URL != "":
+		return t.URL
+	case t.TCP != "":
+		return t.TCP
+	case t.DNS != "":
+		return t.DNS
+	case t.GRPC != "":
+		return t.GRPC
+	default:
+		return strings.Join(t.Exec, " ")
+	}
+}
+
+// probe returns the check of t. onResponse, when set, is called with every
+// response of a url target.
+func (t Target) probe(onResponse func(*http.Response)) (healthcheck.Check, error) {
+	kind, err := t.kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind != kindHTTP && (len(t.Status) > 0 || t.Body != "" || t.BodyRegex != "" || len(t.JSON) > 0 || len(t.Headers) > 0) {
+		return nil, fmt.Errorf("status, body, body_regex, json and headers only apply to url targets")
+	}
+	if kind != kindGRPC && (t.GRPCService != "" || t.GRPCTLS) {
+		return nil, fmt.Errorf("grpc_service and grpc_tls only apply to grpc targets")
+	}
+	switch kind {
+	case kindTCP:
+		if err := hostPort(t.TCP); err != nil {
+			return nil, fmt.Errorf("tcp: %w", err)
+		}
+		return (&healthcheck.TCPProbe{Address: t.TCP}).Check, nil
+	case kindDNS:
+		if strings.ContainsAny(t.DNS, ":/ ") {
+			return nil, fmt.Errorf("dns %q must be a host name", t.DNS)
+		}
+		return (&healthcheck.DNSProbe{Host: t.DNS}).Check, nil
+	case kindGRPC:
+		if err := hostPort(t.GRPC); err != nil {
+			return nil, fmt.Errorf("grpc: %w", err)
+		}
+		return (&healthcheck.GRPCProbe{Address: t.GRPC, Service: t.GRPCService, TLS: t.GRPCTLS}).Check, nil
+	case kindExec:
+		return (&healthcheck.ExecProbe{Command: t.Exec}).Check, nil
+	}
+	probe, err := t.httpProbe()
+	if err != nil {
+		return nil, err
+	}
+	probe.OnResponse = onResponse
+	return probe.Check, nil
+}
+
+// hostPort checks that address is a host:port with both parts set.
+func hostPort(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "" || port == "" {
+		return fmt.Errorf("address %q needs a host and a port", address)
+	}
+	return nil
+}
+
+// httpProbe returns the probe of a url target.
+func (t Target) httpProbe() (*healthcheck.HTTPProbe, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url %q must be an absolute http or https URL", t.URL)
+	}
+	probe := &healthcheck.HTTPProbe{URL: t.URL, Status: t.Status}
+	var body []string
+	if t.Body != "" {
+		body = append(body, regexp.QuoteMeta(t.Body))
+	}
+	if t.BodyRegex != "" {
+		if _, err := regexp.Compile(t.BodyRegex); err != nil {
+			return nil, fmt.Errorf("body_regex: %w", err)
+		}
+		body = append(body, t.BodyRegex)
+	}
+	switch len(body) {
+	case 1:
+		probe.Body = regexp.MustCompile(body[0])
+	case 2:
+		return nil, fmt.Errorf("body and body_regex are mutually exclusive")
+	}
+	for _, expr := range t.JSON {
+		assertion, err := healthcheck.ParseJSONAssertion(expr)
+		if err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		probe.JSON = append(probe.JSON, assertion)
+	}
+	if len(t.Headers) > 0 {
+		probe.Headers = make(map[string]*regexp.Regexp, len(t.Headers))
+	}
+	for name, pattern := range t.Headers {
+		if pattern == "" {
+			probe.Headers[name] = nil
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("headers.%s: %w", name, err)
+		}
+		probe.Headers[name] = re
+	}
+	return probe, nil
+}
+
+// StatusCodes are status codes and ranges of them, written as numbers or
+// as strings such as "2xx" or "200-299".
+type StatusCodes []healthcheck.StatusRange
+
+func (s *StatusCodes) UnmarshalYAML(node *yaml.Node) error {
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	codes := make(StatusCodes, 0, len(values))
+	for _, value := range values {
+		r, err := healthcheck.ParseStatusRange(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		codes = append(codes, r)
+	}
+	*s = codes
+	return nil
+}